@@ -3,6 +3,15 @@ module github.com/volodymyrprokopyuk/go-util
 go 1.25.4
 
 require (
+	github.com/jackc/pgx/v5 v5.11.0
 	github.com/stripe/stripe-go/v82 v82.5.1
 	github.com/urfave/cli/v3 v3.6.1
 )
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
+)
@@ -0,0 +1,106 @@
+package ucache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+  key K
+  val V
+  expiry time.Time
+}
+
+// Size-bounded LRU cache with per-entry TTL, safe for concurrent use
+type Cache[K comparable, V any] struct {
+  mtx sync.Mutex
+  size int
+  ll *list.List
+  items map[K]*list.Element
+}
+
+func New[K comparable, V any](size int) *Cache[K, V] {
+  return &Cache[K, V]{
+    size: max(size, 1),
+    ll: list.New(),
+    items: make(map[K]*list.Element),
+  }
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+  c.ll.Remove(elem)
+  delete(c.items, elem.Value.(*entry[K, V]).key)
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  var zero V
+  elem, exist := c.items[key]
+  if !exist {
+    return zero, false
+  }
+  ent := elem.Value.(*entry[K, V])
+  if time.Now().After(ent.expiry) {
+    c.remove(elem)
+    return zero, false
+  }
+  c.ll.MoveToFront(elem)
+  return ent.val, true
+}
+
+// Evicts the least recently used entry when full
+func (c *Cache[K, V]) Set(key K, val V, ttl time.Duration) {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  expiry := time.Now().Add(ttl)
+  elem, exist := c.items[key]
+  if exist {
+    ent := elem.Value.(*entry[K, V])
+    ent.val, ent.expiry = val, expiry
+    c.ll.MoveToFront(elem)
+    return
+  }
+  elem = c.ll.PushFront(&entry[K, V]{key: key, val: val, expiry: expiry})
+  c.items[key] = elem
+  for c.ll.Len() > c.size {
+    c.remove(c.ll.Back())
+  }
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  elem, exist := c.items[key]
+  if exist {
+    c.remove(elem)
+  }
+}
+
+// Invalidates all entries matching the key predicate
+func (c *Cache[K, V]) DeleteFunc(match func(key K) bool) int {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  deleted := 0
+  for key, elem := range c.items {
+    if match(key) {
+      c.remove(elem)
+      deleted++
+    }
+  }
+  return deleted
+}
+
+func (c *Cache[K, V]) Clear() {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  c.ll.Init()
+  clear(c.items)
+}
+
+func (c *Cache[K, V]) Len() int {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  return c.ll.Len()
+}
@@ -1,8 +1,13 @@
 package ucheck_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/volodymyrprokopyuk/go-util/ucheck"
 	"github.com/volodymyrprokopyuk/go-util/urand"
@@ -108,15 +113,20 @@ func TestCheckContainsAnySuccess(t *testing.T) {
 }
 
 func TestCheckEmailSuccess(t *testing.T) {
-  email := urand.RandEmail()
-  if !ucheck.CheckEmail(email) {
-    t.Errorf("invalid email: %s", email)
+  for _, email := range []string{
+    urand.RandEmail(), urand.RandEmail(urand.ReservedDomains()),
+    urand.RandEmail(urand.DomainPool("staging.internal")),
+  } {
+    if !ucheck.CheckEmail(email) {
+      t.Errorf("invalid email: %s", email)
+    }
   }
 }
 
 func TestCheckURLSuccess(t *testing.T) {
   for _, url := range []string{
-    urand.RandURL(), "https://host.domain.org", "https://host.domain.org:1234",
+    urand.RandURL(), urand.RandURL(urand.ReservedDomains()),
+    "https://host.domain.org", "https://host.domain.org:1234",
   } {
     if !ucheck.CheckURL(url) {
       t.Errorf("invalid URL: %s", url)
@@ -155,6 +165,54 @@ func TestCheckPortSuccess(t *testing.T) {
   }
 }
 
+func TestCheckHostPortSuccess(t *testing.T) {
+  cases := []struct{
+    name string
+    hostPort string
+    valid bool
+  }{
+    {"IPv4", "10.0.0.1:5432", true},
+    {"IPv6", "[::1]:8080", true},
+    {"DNS name", "db.internal.example.com:5432", true},
+    {"localhost", "localhost:80", true},
+    {"invalid port range", "localhost:99999", false},
+    {"missing port", "localhost", false},
+    {"unbracketed IPv6", "::1:8080", false},
+    {"bracketed IPv4", "[10.0.0.1]:80", false},
+    {"invalid DNS label", "-db.example.com:5432", false},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T){
+      valid := ucheck.CheckHostPort(c.hostPort)
+      if valid != c.valid {
+        t.Errorf("expected %v, got %v: %s", c.valid, valid, c.hostPort)
+      }
+    })
+  }
+}
+
+func TestCheckURLReachableSuccessFailure(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(
+    func(w http.ResponseWriter, r *http.Request) {
+      if r.URL.Path == "/down" {
+        w.WriteHeader(http.StatusBadGateway)
+      }
+    },
+  ))
+  defer srv.Close()
+  ctx := context.Background()
+  err := ucheck.CheckURLReachable(ctx, srv.URL + "/up")
+  if err != nil {
+    t.Errorf("expected reachable, got %s", err)
+  }
+  err = ucheck.CheckURLReachable(
+    ctx, srv.URL + "/down", ucheck.ReachMethod(http.MethodGet),
+  )
+  if err == nil {
+    t.Errorf("expected unreachable, got reachable")
+  }
+}
+
 func TestCheckIBANSuccessFailure(t *testing.T) {
   country := "es"
   for range 2 {
@@ -171,3 +229,267 @@ func TestCheckIBANSuccessFailure(t *testing.T) {
     }
   }
 }
+
+func TestCheckVariantsSuccessFailure(t *testing.T) {
+  type payment struct {
+    Method string `json:"method"`
+    Card *string `json:"card"`
+    IBAN string `json:"iban"`
+  }
+  card := "4242424242424242"
+  check := ucheck.Variants("method", map[string][]ucheck.CheckFunc[payment]{
+    "card": {ucheck.Require[payment]("card")},
+    "bank_transfer": {ucheck.Require[payment]("iban")},
+  })
+  cases := []struct{
+    name string
+    req payment
+    err string
+  }{
+    {"card", payment{Method: "card", Card: &card}, ""},
+    {"bank transfer", payment{Method: "bank_transfer", IBAN: "ES00"}, ""},
+    {"missing card", payment{Method: "card"}, "card requires card"},
+    {
+      "missing iban", payment{Method: "bank_transfer", Card: &card},
+      "bank_transfer requires iban",
+    },
+    {"missing method", payment{}, "requires method"},
+    {"unknown method", payment{Method: "cash"}, "unknown method cash"},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      err := ucheck.Check(&c.req, check)
+      var msg string
+      if err != nil {
+        msg = err.Error()
+      }
+      if msg != c.err {
+        t.Errorf("expected %q, got %q", c.err, msg)
+      }
+    })
+  }
+}
+
+func TestCheckInListSuccess(t *testing.T) {
+  fsys := fstest.MapFS{"blocked.txt": &fstest.MapFile{
+    Data: []byte("# blocked BINs\n424242\n\n400000 # test\n"),
+  }}
+  blocked, err := ucheck.ListFromFS(fsys, "blocked.txt")
+  if err != nil {
+    t.Fatal(err)
+  }
+  inCountry := ucheck.InList("ES", "PT")
+  notSanctioned := ucheck.NotInList("KP", "IR")
+  cases := []struct{
+    name string
+    check func(val string) bool
+    val string
+    valid bool
+  }{
+    {"in list", inCountry, "es", true},
+    {"not in list", inCountry, "FR", false},
+    {"not in denylist", notSanctioned, "ES", true},
+    {"in denylist", notSanctioned, " ir ", false},
+    {"embedded in", blocked.In, "400000", true},
+    {"embedded not in", blocked.NotIn, "424242", false},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      valid := c.check(c.val)
+      if valid != c.valid {
+        t.Errorf("expected %v, got %v: %s", c.valid, valid, c.val)
+      }
+    })
+  }
+}
+
+func TestCheckURLLoaderSuccess(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(
+    func(w http.ResponseWriter, r *http.Request) {
+      if r.Header.Get("If-None-Match") == `"v1"` {
+        w.WriteHeader(http.StatusNotModified)
+        return
+      }
+      w.Header().Set("ETag", `"v1"`)
+      _, _ = w.Write([]byte("mailinator.com\nyopmail.com\n"))
+    },
+  ))
+  defer srv.Close()
+  ctx := context.Background()
+  load := ucheck.URLLoader(srv.URL)
+  disposable := ucheck.NewList()
+  for range 2 {
+    err := disposable.Load(ctx, load)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if disposable.Len() != 2 || !disposable.Contains("yopmail.com") {
+      t.Errorf("expected 2 domains, got %d", disposable.Len())
+    }
+  }
+}
+
+func TestCheckGenerateSuccessFailure(t *testing.T) {
+  cases := []struct{
+    name string
+    src string
+    code []string
+    err string
+  }{
+    {
+      "required min max",
+      "type req struct {\n  Amount int64 `json:\"amount\" " +
+        "check:\"required,min=1\"`\n  Name *string `check:\"max=5\"`\n}",
+      []string{
+        "func Checkreq(v *req) error {", `errors.New("requires amount")`,
+        "if v.Name != nil {", "len(*v.Name) > 5",
+      },
+      "",
+    },
+    {
+      "email oneof",
+      "type req struct {\n  Email string `check:\"email\"`\n" +
+        "  Currency string `check:\"oneof=EUR USD\"`\n}",
+      []string{
+        "!ucheck.CheckEmail(v.Email)",
+        `v.Currency != "EUR" && v.Currency != "USD"`,
+      },
+      "",
+    },
+    {
+      "unsupported rule", "type req struct {\n  On bool `check:\"min=1\"`\n}",
+      nil, "req.On: unsupported min=1",
+    },
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      code, err := ucheck.Generate("req.go", []byte("package p\n" + c.src))
+      if err != nil {
+        if err.Error() != c.err {
+          t.Errorf("expected %q, got %q", c.err, err)
+        }
+        return
+      }
+      for _, exp := range c.code {
+        if !strings.Contains(string(code), exp) {
+          t.Errorf("expected %s in\n%s", exp, code)
+        }
+      }
+    })
+  }
+}
+
+func TestCheckTimeZoneCronSuccessFailure(t *testing.T) {
+  for _, c := range []struct{
+    name string
+    valid bool
+  }{
+    {"Europe/Madrid", true}, {"UTC", true}, {"Local", false},
+    {"", false}, {"Mars/Olympus", false},
+  } {
+    if ucheck.CheckTimeZone(c.name) != c.valid {
+      t.Errorf("expected %v, got %v: %q", c.valid, !c.valid, c.name)
+    }
+  }
+  now := time.Date(2025, 1, 31, 10, 30, 15, 0, time.UTC) // Friday
+  cases := []struct{
+    name string
+    expr string
+    next time.Time
+    err string
+  }{
+    {"every minute", "* * * * *", now.Add(45 * time.Second), ""},
+    {"seconds", "*/20 * * * * *", now.Add(5 * time.Second), ""},
+    {
+      "weekdays", "0 9 * * MON-FRI",
+      time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC), "",
+    },
+    {"macro", "@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), ""},
+    {
+      "dom or dow", "0 0 15 * 0",
+      time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC), "",
+    },
+    {"never", "0 0 30 feb *", time.Time{}, ""},
+    {"fields", "* * *", time.Time{}, "expected 5 or 6 cron fields, got 3"},
+    {"range", "0 25 * * *", time.Time{}, "invalid hour 25"},
+    {"step", "*/0 * * * *", time.Time{}, "invalid minute step 0"},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      cron, err := ucheck.ParseCron(c.expr)
+      if err != nil {
+        if err.Error() != c.err {
+          t.Errorf("expected %q, got %q", c.err, err)
+        }
+        return
+      }
+      next := cron.Next(now)
+      if !next.Equal(c.next) {
+        t.Errorf("expected %s, got %s", c.next, next)
+      }
+    })
+  }
+}
+
+func TestCheckJSONDepthSizeSuccessFailure(t *testing.T) {
+  cases := []struct{
+    name string
+    data string
+    depth, bytes, elements int
+    err string
+  }{
+    {"flat", `{"a": 1, "b": [1, 2]}`, 2, 100, 4, ""},
+    {"strings", `["[{", "\"]", "a,b"]`, 1, 100, 3, ""},
+    {"empty", `{"a": [], "b": {}}`, 2, 100, 2, ""},
+    {"deep", `[[[[1]]]]`, 3, 100, 10, "JSON depth exceeds 3"},
+    {"bytes", `[1, 2, 3]`, 1, 5, 10, "JSON size exceeds 5 bytes"},
+    {"elements", `[[1, 2], [3, 4]]`, 2, 100, 5, "JSON elements exceed 5"},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      err := ucheck.CheckJSONDepth([]byte(c.data), c.depth)
+      if err == nil {
+        err = ucheck.CheckJSONSize([]byte(c.data), c.bytes, c.elements)
+      }
+      var msg string
+      if err != nil {
+        msg = err.Error()
+      }
+      if msg != c.err {
+        t.Errorf("expected %q, got %q", c.err, msg)
+      }
+    })
+  }
+}
+
+func TestRandTimeRangeSuccess(t *testing.T) {
+  cases := []struct{
+    name string
+    a, b time.Time
+    granularity time.Duration
+  }{
+    {
+      "days", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+      time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Second,
+    },
+    {
+      "sub-second", time.Unix(1000, 100), time.Unix(1000, 900), time.Second,
+    },
+    {
+      "sub-second nanoseconds", time.Unix(1000, 100), time.Unix(1000, 900),
+      time.Nanosecond,
+    },
+    {"empty", time.Unix(1000, 500), time.Unix(1000, 500), time.Second},
+  }
+  for _, c := range cases {
+    t.Run(c.name, func(t *testing.T) {
+      for range 100 {
+        rt := urand.RandTime(c.a, c.b, urand.Granularity(c.granularity))
+        if rt.Before(c.a.Truncate(c.granularity)) || rt.After(c.b) {
+          t.Fatalf("expected [%s, %s], got %s", c.a, c.b, rt)
+        }
+      }
+    })
+  }
+  _ = urand.RandTimeWithin(500 * time.Millisecond)
+}
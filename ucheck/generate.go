@@ -0,0 +1,247 @@
+package ucheck
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type genField struct {
+  name string
+  label string
+  kind string
+  ptr bool
+  float bool
+  rules []string
+}
+
+var genNumbers = []string{
+  "int", "int8", "int16", "int32", "int64",
+  "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64",
+}
+
+// string, number, bool, len or other
+func genKind(expr ast.Expr) (string, bool) {
+  switch e := expr.(type) {
+  case *ast.StarExpr:
+    kind, _ := genKind(e.X)
+    return kind, true
+  case *ast.ArrayType, *ast.MapType:
+    return "len", false
+  case *ast.Ident:
+    switch {
+    case e.Name == "string":
+      return "string", false
+    case e.Name == "bool":
+      return "bool", false
+    case slices.Contains(genNumbers, e.Name):
+      return "number", false
+    }
+  }
+  return "other", false
+}
+
+func genFloat(expr ast.Expr) bool {
+  star, ok := expr.(*ast.StarExpr)
+  if ok {
+    expr = star.X
+  }
+  ident, ok := expr.(*ast.Ident)
+  return ok && (ident.Name == "float32" || ident.Name == "float64")
+}
+
+// Struct fields with check:"required,min=3,max=50,email,oneof=a b" tags
+func genFields(st *ast.StructType) []genField {
+  var fields []genField
+  for _, field := range st.Fields.List {
+    if field.Tag == nil || len(field.Names) == 0 {
+      continue
+    }
+    tag, _ := strconv.Unquote(field.Tag.Value)
+    rules := reflect.StructTag(tag).Get("check")
+    if len(rules) == 0 {
+      continue
+    }
+    label, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+    kind, ptr := genKind(field.Type)
+    for _, name := range field.Names {
+      f := genField{
+        name: name.Name, label: label, kind: kind, ptr: ptr,
+        float: genFloat(field.Type), rules: strings.Split(rules, ","),
+      }
+      if len(f.label) == 0 || f.label == "-" {
+        f.label = name.Name
+      }
+      fields = append(fields, f)
+    }
+  }
+  return fields
+}
+
+func genRule(
+  buf *bytes.Buffer, f genField, rule, prefix string,
+) (bool, error) {
+  val := "v." + f.name
+  if f.ptr {
+    val = "*v." + f.name
+  }
+  fail := func(cond, msg string) {
+    fmt.Fprintf(buf, "if %s {\nreturn errors.New(%q)\n}\n", cond, msg)
+  }
+  name, arg, _ := strings.Cut(rule, "=")
+  // Lengths and integer fields compare with integers only
+  validNumber := func(arg string) bool {
+    if f.kind == "number" && f.float {
+      _, err := strconv.ParseFloat(arg, 64)
+      return err == nil
+    }
+    _, err := strconv.ParseInt(arg, 10, 64)
+    return err == nil
+  }
+  switch {
+  case name == "required" && f.ptr:
+    fail("v." + f.name + " == nil", "requires " + f.label)
+  case name == "required" && f.kind == "string":
+    fail(val + ` == ""`, "requires " + f.label)
+  case name == "required" && f.kind == "number":
+    fail(val + " == 0", "requires " + f.label)
+  case name == "required" && f.kind == "bool":
+    fail("!" + val, "requires " + f.label)
+  case name == "required" && f.kind == "len":
+    fail("len(" + val + ") == 0", "requires " + f.label)
+  case (name == "min" || name == "max") && len(arg) > 0:
+    if !validNumber(arg) {
+      return false, fmt.Errorf("%s: invalid %s", f.name, rule)
+    }
+    op := map[string]string{"min": "<", "max": ">"}[name]
+    switch f.kind {
+    case "string", "len":
+      fail(
+        fmt.Sprintf("len(%s) %s %s", val, op, arg),
+        fmt.Sprintf("invalid %s: %s length %s", f.label, name, arg),
+      )
+    case "number":
+      fail(
+        fmt.Sprintf("%s %s %s", val, op, arg),
+        fmt.Sprintf("invalid %s: %s %s", f.label, name, arg),
+      )
+    default:
+      return false, fmt.Errorf("%s: unsupported %s", f.name, rule)
+    }
+  case name == "oneof" && len(arg) > 0 &&
+    (f.kind == "string" || f.kind == "number"):
+    values := strings.Fields(arg)
+    conds := make([]string, len(values))
+    for i, value := range values {
+      if f.kind == "string" {
+        value = strconv.Quote(value)
+      } else if !validNumber(value) {
+        return false, fmt.Errorf("%s: invalid %s", f.name, rule)
+      }
+      conds[i] = fmt.Sprintf("%s != %s", val, value)
+    }
+    msg := fmt.Sprintf(
+      "invalid %s: one of %s", f.label, strings.Join(values, ", "),
+    )
+    fail(strings.Join(conds, " && "), msg)
+  case f.kind == "string" &&
+    slices.Contains([]string{"email", "url", "ip", "port", "hostname"}, name):
+    check := map[string]string{
+      "email": "CheckEmail", "url": "CheckURL", "ip": "CheckIP",
+      "port": "CheckPort", "hostname": "CheckHostname",
+    }[name]
+    fail(fmt.Sprintf("!%s%s(%s)", prefix, check, val), "invalid " + f.label)
+    return true, nil
+  default:
+    return false, fmt.Errorf("%s: unsupported %s", f.name, rule)
+  }
+  return false, nil
+}
+
+// Emits reflection-free Check<Type>(v *Type) error functions from the check
+// struct tags of the source types, all tagged structs when no types are given
+func Generate(filename string, src []byte, types ...string) ([]byte, error) {
+  fset := token.NewFileSet()
+  file, err := parser.ParseFile(
+    fset, filename, src, parser.SkipObjectResolution,
+  )
+  if err != nil {
+    return nil, err
+  }
+  pkg := file.Name.Name
+  prefix := "ucheck."
+  if pkg == "ucheck" {
+    prefix = ""
+  }
+  var body bytes.Buffer
+  usesCheck := false
+  found := make(map[string]bool)
+  for decl := range ast.Preorder(file) {
+    spec, ok := decl.(*ast.TypeSpec)
+    if !ok {
+      continue
+    }
+    st, ok := spec.Type.(*ast.StructType)
+    typ := spec.Name.Name
+    if !ok || len(types) > 0 && !slices.Contains(types, typ) {
+      continue
+    }
+    fields := genFields(st)
+    if len(fields) == 0 {
+      continue
+    }
+    found[typ] = true
+    fmt.Fprintf(&body, "\nfunc Check%s(v *%s) error {\n", typ, typ)
+    for _, f := range fields {
+      rules := f.rules
+      if f.ptr && slices.Contains(rules, "required") {
+        _, _ = genRule(&body, f, "required", prefix)
+      }
+      rules = slices.DeleteFunc(slices.Clone(rules), func(rule string) bool {
+        return rule == "required" && f.ptr
+      })
+      if len(rules) == 0 {
+        continue
+      }
+      if f.ptr {
+        fmt.Fprintf(&body, "if v.%s != nil {\n", f.name)
+      }
+      for _, rule := range rules {
+        uses, err := genRule(&body, f, strings.TrimSpace(rule), prefix)
+        if err != nil {
+          return nil, fmt.Errorf("%s.%w", typ, err)
+        }
+        usesCheck = usesCheck || uses
+      }
+      if f.ptr {
+        body.WriteString("}\n")
+      }
+    }
+    body.WriteString("return nil\n}\n")
+  }
+  for _, typ := range types {
+    if !found[typ] {
+      return nil, fmt.Errorf("%s: no struct with check tags", typ)
+    }
+  }
+  var out bytes.Buffer
+  fmt.Fprintf(
+    &out, "// Code generated by ucheckgen. DO NOT EDIT.\n\npackage %s\n\n", pkg,
+  )
+  if len(found) > 0 {
+    out.WriteString("import (\n\"errors\"\n")
+    if usesCheck && len(prefix) > 0 {
+      out.WriteString("\n\"github.com/volodymyrprokopyuk/go-util/ucheck\"\n")
+    }
+    out.WriteString(")\n")
+  }
+  out.Write(body.Bytes())
+  return format.Source(out.Bytes())
+}
@@ -0,0 +1,195 @@
+package ucheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+)
+
+func normalize(val string) string {
+  return strings.ToLower(strings.TrimSpace(val))
+}
+
+// Case-insensitive set of values replaced atomically on refresh
+type List struct {
+  mtx sync.RWMutex
+  values map[string]struct{}
+}
+
+func NewList(values ...string) *List {
+  l := &List{}
+  l.Replace(values)
+  return l
+}
+
+func (l *List) Replace(values []string) {
+  set := make(map[string]struct{}, len(values))
+  for _, val := range values {
+    val = normalize(val)
+    if len(val) > 0 {
+      set[val] = struct{}{}
+    }
+  }
+  l.mtx.Lock()
+  defer l.mtx.Unlock()
+  l.values = set
+}
+
+func (l *List) Len() int {
+  l.mtx.RLock()
+  defer l.mtx.RUnlock()
+  return len(l.values)
+}
+
+func (l *List) Contains(val string) bool {
+  l.mtx.RLock()
+  defer l.mtx.RUnlock()
+  _, exist := l.values[normalize(val)]
+  return exist
+}
+
+func (l *List) In(val string) bool {
+  return l.Contains(val)
+}
+
+func (l *List) NotIn(val string) bool {
+  return !l.Contains(val)
+}
+
+func InList(values ...string) func(val string) bool {
+  return NewList(values...).In
+}
+
+func NotInList(values ...string) func(val string) bool {
+  return NewList(values...).NotIn
+}
+
+// One value per line, blank lines and # comments are skipped
+func parseList(r io.Reader) ([]string, error) {
+  var values []string
+  scn := bufio.NewScanner(r)
+  for scn.Scan() {
+    line, _, _ := strings.Cut(scn.Text(), "#")
+    line = strings.TrimSpace(line)
+    if len(line) > 0 {
+      values = append(values, line)
+    }
+  }
+  return values, scn.Err()
+}
+
+// e.g. //go:embed lists/disposable.txt
+func ListFromFS(fsys fs.FS, name string) (*List, error) {
+  data, err := fs.ReadFile(fsys, name)
+  if err != nil {
+    return nil, err
+  }
+  values, err := parseList(bytes.NewReader(data))
+  if err != nil {
+    return nil, err
+  }
+  return NewList(values...), nil
+}
+
+// Loader returns nil values without error when the list is unchanged
+type ListLoader func(ctx context.Context) ([]string, error)
+
+type loaderConfig struct {
+  httpc *ureq.Client
+}
+
+type loaderOption func(cfg *loaderConfig)
+
+// Client of the list host, a client with a 10s timeout by default
+func LoaderClient(httpc *ureq.Client) loaderOption {
+  return func(cfg *loaderConfig) {
+    cfg.httpc = httpc
+  }
+}
+
+// Fetches the list with ETag caching, 304 keeps the current values
+func URLLoader(url string, opts ...loaderOption) ListLoader {
+  cfg := &loaderConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  if cfg.httpc == nil {
+    cfg.httpc = ureq.NewClient(ureq.Timeout(10 * time.Second))
+  }
+  var mtx sync.Mutex
+  var etag string
+  return func(ctx context.Context) ([]string, error) {
+    mtx.Lock()
+    tag := etag
+    mtx.Unlock()
+    var values []string
+    stream := ureq.ResStream(func(body io.Reader) error {
+      var err error
+      values, err = parseList(body)
+      return err
+    })
+    var res *http.Response
+    var err error
+    if len(tag) > 0 {
+      res, err = cfg.httpc.GET(
+        ctx, ureq.URL(url), ureq.Header("If-None-Match", tag), stream,
+      )
+    } else {
+      res, err = cfg.httpc.GET(ctx, ureq.URL(url), stream)
+    }
+    if err != nil {
+      return nil, err
+    }
+    switch res.StatusCode {
+    case http.StatusOK:
+    case http.StatusNotModified:
+      return nil, nil
+    default:
+      return nil, fmt.Errorf("list %s: status %d", url, res.StatusCode)
+    }
+    mtx.Lock()
+    etag = res.Header.Get("ETag")
+    mtx.Unlock()
+    if values == nil {
+      values = []string{}
+    }
+    return values, nil
+  }
+}
+
+func (l *List) Load(ctx context.Context, load ListLoader) error {
+  values, err := load(ctx)
+  if err != nil {
+    return err
+  }
+  if values != nil {
+    l.Replace(values)
+  }
+  return nil
+}
+
+// Reloads the list every interval until ctx is canceled, failed reloads keep
+// the current values. Suitable for userv.Background
+func (l *List) Refresh(
+  ctx context.Context, interval time.Duration, load ListLoader,
+) error {
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-ctx.Done():
+      return nil
+    case <-ticker.C:
+      _ = l.Load(ctx, load)
+    }
+  }
+}
@@ -0,0 +1,91 @@
+package ucheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var reDNSLabel = regexp.MustCompile(`^[a-zA-Z\d]([-a-zA-Z\d]{0,61}[a-zA-Z\d])?$`)
+
+func CheckHostname(host string) bool {
+  host = strings.TrimSuffix(host, ".")
+  if len(host) == 0 || len(host) > 253 {
+    return false
+  }
+  for label := range strings.SplitSeq(host, ".") {
+    if !reDNSLabel.MatchString(label) {
+      return false
+    }
+  }
+  return true
+}
+
+// IPv6 hosts must be bracketed: [::1]:8080
+func CheckHostPort(hostPort string) bool {
+  host, port, err := net.SplitHostPort(hostPort)
+  if err != nil || !CheckPort(port) {
+    return false
+  }
+  if strings.Contains(hostPort, "[") {
+    addr, err := netip.ParseAddr(host)
+    return err == nil && addr.Is6()
+  }
+  _, err = netip.ParseAddr(host)
+  return err == nil || CheckHostname(host)
+}
+
+type reachConfig struct {
+  method string
+  timeout time.Duration
+}
+
+type reachOption func(cfg *reachConfig)
+
+func ReachMethod(method string) reachOption {
+  return func(cfg *reachConfig) {
+    cfg.method = method
+  }
+}
+
+func ReachTimeout(timeout time.Duration) reachOption {
+  return func(cfg *reachConfig) {
+    cfg.timeout = timeout
+  }
+}
+
+// Any non-5xx response is considered reachable
+func CheckURLReachable(
+  ctx context.Context, url string, opts ...reachOption,
+) error {
+  cfg := &reachConfig{
+    method: http.MethodHead,
+    timeout: 5 * time.Second,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  if !CheckURL(url) {
+    return fmt.Errorf("invalid URL: %s", url)
+  }
+  ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+  defer cancel()
+  req, err := http.NewRequestWithContext(ctx, cfg.method, url, http.NoBody)
+  if err != nil {
+    return err
+  }
+  res, err := http.DefaultClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("unreachable URL %s: %w", url, err)
+  }
+  _ = res.Body.Close()
+  if res.StatusCode >= 500 {
+    return fmt.Errorf("unreachable URL %s: status %d", url, res.StatusCode)
+  }
+  return nil
+}
@@ -0,0 +1,74 @@
+package ucheck
+
+import (
+	"fmt"
+)
+
+// Scans the raw bytes, fn sees the characters outside strings and the
+// opening quote of every string
+func scanJSON(data []byte, fn func(c byte) error) error {
+  inString, escaped := false, false
+  for _, c := range data {
+    switch {
+    case escaped:
+      escaped = false
+    case inString && c == '\\':
+      escaped = true
+    case inString && c == '"':
+      inString = false
+    case inString:
+    default:
+      inString = c == '"'
+      err := fn(c)
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
+
+// Rejects nesting deeper than maxDepth before decoding, syntax is left to
+// the decoder
+func CheckJSONDepth(data []byte, maxDepth int) error {
+  depth := 0
+  return scanJSON(data, func(c byte) error {
+    switch c {
+    case '{', '[':
+      depth++
+      if depth > maxDepth {
+        return fmt.Errorf("JSON depth exceeds %d", maxDepth)
+      }
+    case '}', ']':
+      depth--
+    }
+    return nil
+  })
+}
+
+func jsonSpace(c byte) bool {
+  return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Rejects payloads over maxBytes or with more than maxElements array items
+// and object members in total before decoding
+func CheckJSONSize(data []byte, maxBytes, maxElements int) error {
+  if len(data) > maxBytes {
+    return fmt.Errorf("JSON size exceeds %d bytes", maxBytes)
+  }
+  elements, opened := 0, false
+  return scanJSON(data, func(c byte) error {
+    if jsonSpace(c) {
+      return nil
+    }
+    // The first element of a non-empty container and every comma
+    if opened && c != '}' && c != ']' || c == ',' {
+      elements++
+    }
+    opened = c == '{' || c == '['
+    if elements > maxElements {
+      return fmt.Errorf("JSON elements exceed %d", maxElements)
+    }
+    return nil
+  })
+}
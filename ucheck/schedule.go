@@ -0,0 +1,186 @@
+package ucheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IANA time zone name e.g. Europe/Madrid, Local is rejected
+func CheckTimeZone(name string) bool {
+  if len(name) == 0 || name == "Local" {
+    return false
+  }
+  _, err := time.LoadLocation(name)
+  return err == nil
+}
+
+type cronField struct {
+  name string
+  min, max int
+  names []string
+}
+
+var cronFields = []cronField{
+  {name: "second", min: 0, max: 59},
+  {name: "minute", min: 0, max: 59},
+  {name: "hour", min: 0, max: 23},
+  {name: "day of month", min: 1, max: 31},
+  {name: "month", min: 1, max: 12, names: []string{
+    "", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct",
+    "nov", "dec",
+  }},
+  {name: "day of week", min: 0, max: 7, names: []string{
+    "sun", "mon", "tue", "wed", "thu", "fri", "sat",
+  }},
+}
+
+var cronMacros = map[string]string{
+  "@yearly": "0 0 1 1 *",
+  "@annually": "0 0 1 1 *",
+  "@monthly": "0 0 1 * *",
+  "@weekly": "0 0 * * 0",
+  "@daily": "0 0 * * *",
+  "@midnight": "0 0 * * *",
+  "@hourly": "0 * * * *",
+}
+
+// Parsed cron schedule, bit i of a field is set when the value i matches
+type Cron struct {
+  second, minute, hour, dom, month, dow uint64
+  domAny, dowAny bool
+}
+
+func (f cronField) value(val string) (int, error) {
+  for i, name := range f.names {
+    if len(name) > 0 && strings.EqualFold(val, name) {
+      return i, nil
+    }
+  }
+  n, err := strconv.Atoi(val)
+  if err != nil || n < f.min || n > f.max {
+    return 0, fmt.Errorf("invalid %s %s", f.name, val)
+  }
+  return n, nil
+}
+
+// Lists of *, values, ranges a-b and steps */n or a-b/n
+func (f cronField) parse(expr string) (uint64, error) {
+  var bits uint64
+  for part := range strings.SplitSeq(expr, ",") {
+    rng, stepStr, hasStep := strings.Cut(part, "/")
+    step := 1
+    if hasStep {
+      var err error
+      step, err = strconv.Atoi(stepStr)
+      if err != nil || step < 1 {
+        return 0, fmt.Errorf("invalid %s step %s", f.name, stepStr)
+      }
+    }
+    lo, hi := f.min, f.max
+    if rng != "*" {
+      loStr, hiStr, isRange := strings.Cut(rng, "-")
+      var err error
+      lo, err = f.value(loStr)
+      if err != nil {
+        return 0, err
+      }
+      hi = lo
+      if isRange {
+        hi, err = f.value(hiStr)
+        if err != nil {
+          return 0, err
+        }
+      } else if hasStep {
+        hi = f.max
+      }
+      if lo > hi {
+        return 0, fmt.Errorf("invalid %s range %s", f.name, rng)
+      }
+    }
+    for i := lo; i <= hi; i += step {
+      bits |= 1 << i
+    }
+  }
+  return bits, nil
+}
+
+// Five fields minute hour dom month dow or six fields with leading seconds,
+// month and weekday names and @daily-style macros are accepted
+func ParseCron(expr string) (*Cron, error) {
+  macro, exist := cronMacros[strings.TrimSpace(expr)]
+  if exist {
+    expr = macro
+  }
+  fields := strings.Fields(expr)
+  switch len(fields) {
+  case 5:
+    fields = append([]string{"0"}, fields...)
+  case 6:
+  default:
+    return nil, fmt.Errorf("expected 5 or 6 cron fields, got %d", len(fields))
+  }
+  var bits [6]uint64
+  for i, field := range cronFields {
+    var err error
+    bits[i], err = field.parse(fields[i])
+    if err != nil {
+      return nil, err
+    }
+  }
+  dow := bits[5]
+  if dow & (1 << 7) != 0 { // 7 is Sunday too
+    dow |= 1
+  }
+  return &Cron{
+    second: bits[0], minute: bits[1], hour: bits[2], dom: bits[3],
+    month: bits[4], dow: dow,
+    domAny: strings.HasPrefix(fields[3], "*"),
+    dowAny: strings.HasPrefix(fields[5], "*"),
+  }, nil
+}
+
+func CheckCron(expr string) bool {
+  _, err := ParseCron(expr)
+  return err == nil
+}
+
+func bitSet(bits uint64, i int) bool {
+  return bits & (1 << i) != 0
+}
+
+// Restricted day of month and day of week match either, as in cron
+func (c *Cron) dayMatch(t time.Time) bool {
+  dom, dow := bitSet(c.dom, t.Day()), bitSet(c.dow, int(t.Weekday()))
+  if c.domAny || c.dowAny {
+    return dom && dow
+  }
+  return dom || dow
+}
+
+// First run strictly after t in the location of t, zero when the schedule
+// never runs e.g. 0 0 30 2 *
+func (c *Cron) Next(t time.Time) time.Time {
+  loc := t.Location()
+  t = t.Truncate(time.Second).Add(time.Second)
+  limit := t.AddDate(5, 0, 0)
+  for t.Before(limit) {
+    y, m, d := t.Date()
+    switch {
+    case !bitSet(c.month, int(m)):
+      t = time.Date(y, m + 1, 1, 0, 0, 0, 0, loc)
+    case !c.dayMatch(t):
+      t = time.Date(y, m, d + 1, 0, 0, 0, 0, loc)
+    case !bitSet(c.hour, t.Hour()):
+      t = time.Date(y, m, d, t.Hour() + 1, 0, 0, 0, loc)
+    case !bitSet(c.minute, t.Minute()):
+      t = time.Date(y, m, d, t.Hour(), t.Minute() + 1, 0, 0, loc)
+    case !bitSet(c.second, t.Second()):
+      t = t.Add(time.Second)
+    default:
+      return t
+    }
+  }
+  return time.Time{}
+}
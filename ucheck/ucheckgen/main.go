@@ -0,0 +1,50 @@
+// Generates reflection-free validators from check struct tags e.g.
+//
+//   //go:generate go run github.com/volodymyrprokopyuk/go-util/ucheck/ucheckgen -type CreateReq
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/ucheck"
+)
+
+func main() {
+  types := flag.String("type", "", "comma-separated struct types")
+  output := flag.String("output", "", "output file, <file>_check.go")
+  flag.Parse()
+  file := os.Getenv("GOFILE")
+  if flag.NArg() > 0 {
+    file = flag.Arg(0)
+  }
+  if len(file) == 0 {
+    fmt.Fprintln(os.Stderr, "ucheckgen: source file must be provided")
+    os.Exit(1)
+  }
+  src, err := os.ReadFile(file)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "ucheckgen: %s\n", err)
+    os.Exit(1)
+  }
+  var typs []string
+  if len(*types) > 0 {
+    typs = strings.Split(*types, ",")
+  }
+  code, err := ucheck.Generate(file, src, typs...)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "ucheckgen: %s\n", err)
+    os.Exit(1)
+  }
+  out := *output
+  if len(out) == 0 {
+    out = strings.TrimSuffix(file, ".go") + "_check.go"
+  }
+  err = os.WriteFile(out, code, 0o644)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "ucheckgen: %s\n", err)
+    os.Exit(1)
+  }
+}
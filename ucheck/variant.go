@@ -0,0 +1,65 @@
+package ucheck
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Struct field by Go name or JSON tag name
+func fieldByName(val any, name string) (reflect.Value, bool) {
+  v := reflect.Indirect(reflect.ValueOf(val))
+  if v.Kind() != reflect.Struct {
+    return reflect.Value{}, false
+  }
+  t := v.Type()
+  for i := range t.NumField() {
+    field := t.Field(i)
+    tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+    if field.Name == name || tag == name {
+      return v.Field(i), true
+    }
+  }
+  return reflect.Value{}, false
+}
+
+// Fails with "requires <field>" for zero or missing fields
+func Require[T any](fields ...string) CheckFunc[T] {
+  return func(val *T) error {
+    for _, name := range fields {
+      field, exist := fieldByName(val, name)
+      if !exist || field.IsZero() {
+        return fmt.Errorf("requires %s", name)
+      }
+    }
+    return nil
+  }
+}
+
+// Runs the checks of the variant selected by the string discriminator field,
+// errors are prefixed with the variant e.g. "bank_transfer requires iban"
+func Variants[T any](
+  discriminator string, variants map[string][]CheckFunc[T],
+) CheckFunc[T] {
+  return func(val *T) error {
+    field, exist := fieldByName(val, discriminator)
+    if !exist {
+      return fmt.Errorf("missing discriminator %s", discriminator)
+    }
+    field = reflect.Indirect(field)
+    if !field.IsValid() || field.Kind() != reflect.String ||
+      len(field.String()) == 0 {
+      return fmt.Errorf("requires %s", discriminator)
+    }
+    variant := field.String()
+    checks, exist := variants[variant]
+    if !exist {
+      return fmt.Errorf("unknown %s %s", discriminator, variant)
+    }
+    err := Check(val, checks...)
+    if err != nil {
+      return fmt.Errorf("%s %w", variant, err)
+    }
+    return nil
+  }
+}
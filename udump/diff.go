@@ -0,0 +1,46 @@
+package udump
+
+import (
+	"bytes"
+)
+
+// Line-based LCS diff, - lines from a, + lines from b
+func Diff(a, b []byte) []byte {
+  la := bytes.Split(bytes.TrimSuffix(a, []byte("\n")), []byte("\n"))
+  lb := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+  n, m := len(la), len(lb)
+  lcs := make([][]int, n + 1)
+  for i := range lcs {
+    lcs[i] = make([]int, m + 1)
+  }
+  for i := n - 1; i >= 0; i-- {
+    for j := m - 1; j >= 0; j-- {
+      if bytes.Equal(la[i], lb[j]) {
+        lcs[i][j] = lcs[i + 1][j + 1] + 1
+      } else {
+        lcs[i][j] = max(lcs[i + 1][j], lcs[i][j + 1])
+      }
+    }
+  }
+  var diff bytes.Buffer
+  i, j := 0, 0
+  for i < n || j < m {
+    switch {
+    case i < n && j < m && bytes.Equal(la[i], lb[j]):
+      diff.WriteString("  ")
+      diff.Write(la[i])
+      i++
+      j++
+    case j < m && (i == n || lcs[i][j + 1] >= lcs[i + 1][j]):
+      diff.WriteString("+ ")
+      diff.Write(lb[j])
+      j++
+    default:
+      diff.WriteString("- ")
+      diff.Write(la[i])
+      i++
+    }
+    diff.WriteByte('\n')
+  }
+  return diff.Bytes()
+}
@@ -0,0 +1,178 @@
+package udump
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type sinkConfig struct {
+  gzip bool
+}
+
+type sinkOption func(cfg *sinkConfig)
+
+// Compresses rotated files to path.<timestamp>.gz
+func SinkGzip() sinkOption {
+  return func(cfg *sinkConfig) {
+    cfg.gzip = true
+  }
+}
+
+// JSON Lines file rotated by size, keeps maxFiles rotated files
+type Sink struct {
+  mtx sync.Mutex
+  path string
+  maxSize int64
+  maxFiles int
+  cfg *sinkConfig
+  file *os.File
+  size int64
+}
+
+func FileSink(
+  path string, maxSizeMB, maxFiles int, opts ...sinkOption,
+) (*Sink, error) {
+  cfg := &sinkConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  s := &Sink{
+    path: path, maxSize: int64(maxSizeMB) << 20, maxFiles: maxFiles, cfg: cfg,
+  }
+  err := s.open()
+  if err != nil {
+    return nil, err
+  }
+  return s, nil
+}
+
+func (s *Sink) open() error {
+  err := os.MkdirAll(filepath.Dir(s.path), 0o750)
+  if err != nil {
+    return err
+  }
+  file, err := os.OpenFile(s.path, os.O_CREATE | os.O_WRONLY | os.O_APPEND, 0o640)
+  if err != nil {
+    return err
+  }
+  info, err := file.Stat()
+  if err != nil {
+    _ = file.Close()
+    return err
+  }
+  s.file, s.size = file, info.Size()
+  return nil
+}
+
+func gzipFile(path string) error {
+  src, err := os.Open(path)
+  if err != nil {
+    return err
+  }
+  defer func() {
+    _ = src.Close()
+  }()
+  dst, err := os.OpenFile(path + ".gz", os.O_CREATE | os.O_WRONLY | os.O_TRUNC, 0o640)
+  if err != nil {
+    return err
+  }
+  gz := gzip.NewWriter(dst)
+  _, err = io.Copy(gz, src)
+  if err == nil {
+    err = gz.Close()
+  }
+  if err == nil {
+    err = dst.Close()
+  } else {
+    _ = dst.Close()
+  }
+  if err != nil {
+    _ = os.Remove(path + ".gz")
+    return err
+  }
+  return os.Remove(path)
+}
+
+// The current file is reopened on every path so a failed rotation does not
+// break later writes
+func (s *Sink) rotate() error {
+  err := s.file.Close()
+  s.file = nil
+  if err != nil {
+    return errors.Join(err, s.open())
+  }
+  stamp := time.Now().UTC().Format("20060102T150405.000000")
+  rotated := fmt.Sprintf("%s.%s", s.path, stamp)
+  err = os.Rename(s.path, rotated)
+  if err != nil {
+    return errors.Join(err, s.open())
+  }
+  err = s.open()
+  if err != nil {
+    return err
+  }
+  if s.cfg.gzip {
+    err = gzipFile(rotated)
+    if err != nil {
+      return err
+    }
+  }
+  files, err := filepath.Glob(s.path + ".*")
+  if err != nil {
+    return err
+  }
+  slices.Sort(files)
+  for len(files) > s.maxFiles {
+    _ = os.Remove(files[0])
+    files = files[1:]
+  }
+  return nil
+}
+
+// Writes p as is e.g. as the userv.LogWriter target
+func (s *Sink) Write(p []byte) (int, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  if s.file == nil {
+    err := s.open()
+    if err != nil {
+      return 0, err
+    }
+  }
+  if s.size > 0 && s.maxSize > 0 && s.size + int64(len(p)) > s.maxSize {
+    err := s.rotate()
+    if err != nil {
+      return 0, err
+    }
+  }
+  n, err := s.file.Write(p)
+  s.size += int64(n)
+  return n, err
+}
+
+// Appends the value as a compact JSON line
+func (s *Sink) Record(val any) error {
+  jval, err := json.Marshal(val)
+  if err != nil {
+    return err
+  }
+  _, err = s.Write(append(jval, '\n'))
+  return err
+}
+
+func (s *Sink) Close() error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  if s.file == nil {
+    return nil
+  }
+  return s.file.Close()
+}
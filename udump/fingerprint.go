@@ -0,0 +1,65 @@
+package udump
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+type Print struct {
+  Hash string `json:"hash"`
+  Shape string `json:"shape"`
+}
+
+func shape(val any) string {
+  switch v := val.(type) {
+  case nil:
+    return "null"
+  case bool:
+    return "bool"
+  case json.Number:
+    return "number"
+  case string:
+    return "string"
+  case []any:
+    var items []string
+    for _, item := range v {
+      s := shape(item)
+      if !slices.Contains(items, s) {
+        items = append(items, s)
+      }
+    }
+    slices.Sort(items)
+    return "[" + strings.Join(items, "|") + "]"
+  case map[string]any:
+    keys := make([]string, 0, len(v))
+    for key := range v {
+      keys = append(keys, key)
+    }
+    slices.Sort(keys)
+    fields := make([]string, len(keys))
+    for i, key := range keys {
+      fields[i] = key + ":" + shape(v[key])
+    }
+    return "{" + strings.Join(fields, ",") + "}"
+  default:
+    return "unknown"
+  }
+}
+
+// Shape keeps keys and value types only, Hash is a short hash of the shape
+func Fingerprint(buf []byte) Print {
+  dec := json.NewDecoder(bytes.NewReader(buf))
+  dec.UseNumber()
+  var val any
+  sh := "invalid"
+  err := dec.Decode(&val)
+  if err == nil {
+    sh = shape(val)
+  }
+  sum := sha256.Sum256([]byte(sh))
+  return Print{Hash: hex.EncodeToString(sum[:6]), Shape: sh}
+}
@@ -0,0 +1,42 @@
+package udump
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func canonical(val any) []byte {
+  buf, isBytes := val.([]byte)
+  if isBytes && json.Valid(buf) {
+    return JSONRaw(buf)
+  }
+  return ValueRaw(val)
+}
+
+// Set UPDATE_GOLDEN=1 to (re)write testdata/<name>.json
+func Golden(t testing.TB, name string, val any) {
+  t.Helper()
+  path := filepath.Join("testdata", name + ".json")
+  got := canonical(val)
+  if len(os.Getenv("UPDATE_GOLDEN")) > 0 {
+    err := os.MkdirAll(filepath.Dir(path), 0o750)
+    if err != nil {
+      t.Fatalf("golden %s: %s", name, err)
+    }
+    err = os.WriteFile(path, got, 0o600)
+    if err != nil {
+      t.Fatalf("golden %s: %s", name, err)
+    }
+    return
+  }
+  exp, err := os.ReadFile(path)
+  if err != nil {
+    t.Fatalf("golden %s: %s (run with UPDATE_GOLDEN=1 to create)", name, err)
+  }
+  if !bytes.Equal(exp, got) {
+    t.Errorf("golden %s mismatch (-expected +got):\n%s", name, Diff(exp, got))
+  }
+}
@@ -0,0 +1,241 @@
+package udump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Renders a body of the content type, the content type keeps its parameters
+// e.g. the multipart boundary
+type Printer func(contentType string, body []byte) []byte
+
+var printers = struct {
+  mtx sync.RWMutex
+  byType map[string]Printer
+}{byType: map[string]Printer{
+  "application/json": printJSON,
+  "application/xml": printXML,
+  "text/xml": printXML,
+  "application/x-www-form-urlencoded": printForm,
+  "multipart/form-data": printMultipart,
+  "multipart/mixed": printMultipart,
+  "application/protobuf": printProtobuf,
+  "application/x-protobuf": printProtobuf,
+}}
+
+// Registers or replaces the printer of the media type e.g. a descriptor-based
+// protobuf printer for application/x-protobuf
+func RegisterPrinter(mediaType string, printer Printer) {
+  printers.mtx.Lock()
+  defer printers.mtx.Unlock()
+  printers.byType[strings.ToLower(mediaType)] = printer
+}
+
+func printer(contentType string) (Printer, bool) {
+  mediaType, _, err := mime.ParseMediaType(contentType)
+  if err != nil {
+    return nil, false
+  }
+  printers.mtx.RLock()
+  defer printers.mtx.RUnlock()
+  printer, exist := printers.byType[mediaType]
+  if exist {
+    return printer, true
+  }
+  // Structured syntax suffix e.g. application/problem+json
+  _, suffix, found := strings.Cut(mediaType, "+")
+  if found {
+    switch suffix {
+    case "json":
+      return printJSON, true
+    case "xml":
+      return printXML, true
+    }
+  }
+  return nil, false
+}
+
+// Content type aware rendering for traces, unknown content types are
+// rendered as JSON when valid, as text when printable or as a size summary
+func Pretty(contentType string, body []byte) []byte {
+  if len(body) == 0 {
+    return body
+  }
+  printer, exist := printer(contentType)
+  if exist {
+    return printer(contentType, body)
+  }
+  if json.Valid(body) {
+    return printJSON(contentType, body)
+  }
+  if utf8.Valid(body) {
+    return body
+  }
+  return fmt.Appendf(nil, "<%d bytes %s>", len(body), contentType)
+}
+
+func printJSON(contentType string, body []byte) []byte {
+  var jbuf bytes.Buffer
+  err := json.Indent(&jbuf, body, "", "  ")
+  if err != nil {
+    return body
+  }
+  return jbuf.Bytes()
+}
+
+func printXML(contentType string, body []byte) []byte {
+  dec := xml.NewDecoder(bytes.NewReader(body))
+  var xbuf bytes.Buffer
+  enc := xml.NewEncoder(&xbuf)
+  enc.Indent("", "  ")
+  for {
+    tok, err := dec.Token()
+    if errors.Is(err, io.EOF) {
+      break
+    }
+    if err != nil {
+      return body
+    }
+    // Whitespace between elements is replaced by the indentation
+    chars, isChars := tok.(xml.CharData)
+    if isChars && len(bytes.TrimSpace(chars)) == 0 {
+      continue
+    }
+    err = enc.EncodeToken(xml.CopyToken(tok))
+    if err != nil {
+      return body
+    }
+  }
+  if enc.Flush() != nil {
+    return body
+  }
+  return xbuf.Bytes()
+}
+
+func printForm(contentType string, body []byte) []byte {
+  form, err := url.ParseQuery(string(body))
+  if err != nil {
+    return body
+  }
+  keys := make([]string, 0, len(form))
+  for key := range form {
+    keys = append(keys, key)
+  }
+  slices.Sort(keys)
+  var fbuf bytes.Buffer
+  for _, key := range keys {
+    for _, value := range form[key] {
+      fmt.Fprintf(&fbuf, "%s: %s\n", key, value)
+    }
+  }
+  return bytes.TrimSuffix(fbuf.Bytes(), []byte("\n"))
+}
+
+// Part names, file names, content types and sizes without the content
+func printMultipart(contentType string, body []byte) []byte {
+  _, params, err := mime.ParseMediaType(contentType)
+  if err != nil || len(params["boundary"]) == 0 {
+    return fmt.Appendf(nil, "<%d bytes %s>", len(body), contentType)
+  }
+  mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+  var mbuf bytes.Buffer
+  for {
+    part, err := mr.NextPart()
+    if errors.Is(err, io.EOF) {
+      break
+    }
+    if err != nil {
+      fmt.Fprintf(&mbuf, "<invalid part: %s>\n", err)
+      break
+    }
+    size, _ := io.Copy(io.Discard, part)
+    fmt.Fprintf(&mbuf, "part %q", part.FormName())
+    if len(part.FileName()) > 0 {
+      fmt.Fprintf(&mbuf, " file %q", part.FileName())
+    }
+    partType := part.Header.Get("Content-Type")
+    if len(partType) > 0 {
+      fmt.Fprintf(&mbuf, " %s", partType)
+    }
+    fmt.Fprintf(&mbuf, " %dB\n", size)
+  }
+  return bytes.TrimSuffix(mbuf.Bytes(), []byte("\n"))
+}
+
+// Wire format field numbers and values without a descriptor
+func protoFields(buf *bytes.Buffer, data []byte, indent string) bool {
+  for len(data) > 0 {
+    key, n := binary.Uvarint(data)
+    if n <= 0 || key >> 3 == 0 {
+      return false
+    }
+    data = data[n:]
+    field := key >> 3
+    switch key & 7 {
+    case 0:
+      val, n := binary.Uvarint(data)
+      if n <= 0 {
+        return false
+      }
+      data = data[n:]
+      fmt.Fprintf(buf, "%s%d: %d\n", indent, field, val)
+    case 1:
+      if len(data) < 8 {
+        return false
+      }
+      fmt.Fprintf(
+        buf, "%s%d: 0x%016x\n", indent, field, binary.LittleEndian.Uint64(data),
+      )
+      data = data[8:]
+    case 5:
+      if len(data) < 4 {
+        return false
+      }
+      fmt.Fprintf(
+        buf, "%s%d: 0x%08x\n", indent, field, binary.LittleEndian.Uint32(data),
+      )
+      data = data[4:]
+    case 2:
+      l, n := binary.Uvarint(data)
+      if n <= 0 || uint64(len(data) - n) < l {
+        return false
+      }
+      val := data[n:n + int(l)]
+      data = data[n + int(l):]
+      var nested bytes.Buffer
+      switch {
+      case len(val) > 0 && protoFields(&nested, val, indent + "  "):
+        fmt.Fprintf(
+          buf, "%s%d: {\n%s%s}\n", indent, field, nested.Bytes(), indent,
+        )
+      case utf8.Valid(val):
+        fmt.Fprintf(buf, "%s%d: %q\n", indent, field, val)
+      default:
+        fmt.Fprintf(buf, "%s%d: <%d bytes>\n", indent, field, len(val))
+      }
+    default:
+      return false
+    }
+  }
+  return true
+}
+
+func printProtobuf(contentType string, body []byte) []byte {
+  var pbuf bytes.Buffer
+  if !protoFields(&pbuf, body, "") {
+    return fmt.Appendf(nil, "<%d bytes %s>", len(body), contentType)
+  }
+  return bytes.TrimSuffix(pbuf.Bytes(), []byte("\n"))
+}
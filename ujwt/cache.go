@@ -0,0 +1,39 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ucache"
+)
+
+// e.g. Verifier.Verify or a closure over JWTRS256Verify
+type VerifyFunc func(ctx context.Context, jwt string) (*JWTClaims, error)
+
+// Caches successful verifications by token hash until the token exp, bounded
+// by maxTTL, so bursts with the same token skip signature verification
+func CachedVerify(
+  verify VerifyFunc, size int, maxTTL time.Duration,
+) VerifyFunc {
+  cache := ucache.New[[sha256.Size]byte, JWTClaims](size)
+  return func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    key := sha256.Sum256([]byte(jwt))
+    cached, exist := cache.Get(key)
+    if exist && timeClaimsCheck(&cached) == nil {
+      return &cached, nil
+    }
+    claims, err := verify(ctx, jwt)
+    if err != nil {
+      return nil, err
+    }
+    ttl := maxTTL
+    if claims.Exp != 0 {
+      ttl = min(ttl, time.Until(time.Unix(claims.Exp, 0)))
+    }
+    if ttl > 0 {
+      cache.Set(key, *claims, ttl)
+    }
+    return claims, nil
+  }
+}
@@ -0,0 +1,50 @@
+package ujwt
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+var (
+  clockSkew atomic.Int64
+  expOptional atomic.Bool
+)
+
+func init() {
+  clockSkew.Store(int64(time.Minute))
+}
+
+// Leeway for exp, nbf and iat checks to tolerate clock drift
+func SetClockSkew(skew time.Duration) {
+  clockSkew.Store(int64(skew))
+}
+
+func ClockSkew() time.Duration {
+  return time.Duration(clockSkew.Load())
+}
+
+// Accepts tokens without exp e.g. of a legacy issuer, exp is required by
+// default
+func SetExpOptional(optional bool) {
+  expOptional.Store(optional)
+}
+
+// Zero nbf and iat are not checked, zero exp only when optional
+func timeClaimsCheck(claims *JWTClaims) error {
+  now, skew := time.Now(), ClockSkew()
+  if claims.Exp == 0 && !expOptional.Load() {
+    return ErrExpired
+  }
+  if claims.Exp != 0 && now.After(time.Unix(claims.Exp, 0).Add(skew)) {
+    return ErrExpired
+  }
+  if claims.Nbf != 0 && now.Before(time.Unix(claims.Nbf, 0).Add(-skew)) {
+    return ErrNotYetValid
+  }
+  if claims.Iat != 0 && now.Before(time.Unix(claims.Iat, 0).Add(-skew)) {
+    return userv.Unautorized("JWT is issued in the future")
+  }
+  return nil
+}
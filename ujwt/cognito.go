@@ -2,9 +2,7 @@ package ujwt
 
 import (
 	"context"
-	"crypto"
 	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
@@ -17,7 +15,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/volodymyrprokopyuk/go-util/ucheck"
 	"github.com/volodymyrprokopyuk/go-util/ureq"
 	"github.com/volodymyrprokopyuk/go-util/userv"
 )
@@ -28,6 +25,9 @@ type jwkt struct {
   Alg string `json:"alg"`
   N string `json:"n"`
   E string `json:"e"`
+  Crv string `json:"crv"`
+  X string `json:"x"`
+  Y string `json:"y"`
 }
 
 type jwkst struct {
@@ -37,13 +37,34 @@ type jwkst struct {
 type jwksCache struct {
   httpc *ureq.Client
   mtx sync.RWMutex
-  keys map[string]*rsa.PublicKey
+  keys map[jwkID]any
+  maxAge time.Duration
+  refreshMtx sync.Mutex
+  stop context.CancelFunc
+  done chan struct{}
+  cfg *jwksConfig
+  fetchMtx sync.Mutex
+  fetching *jwksFetch
+  fetched time.Time
+  unknown map[string]time.Time
+  evictor userv.Evictor
 }
 
-func NewJWKS(httpc *ureq.Client) *jwksCache {
+// The client is nil for JWKSFile, JWKSBytes and JWKSPEM key sources
+func NewJWKS(httpc *ureq.Client, opts ...jwksOption) *jwksCache {
+  cfg := &jwksConfig{
+    url: "/.well-known/jwks.json",
+    minRefetch: 30 * time.Second,
+    unknownTTL: 5 * time.Minute,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
   return &jwksCache{
     httpc: httpc,
-    keys: make(map[string]*rsa.PublicKey),
+    keys: make(map[jwkID]any),
+    cfg: cfg,
+    unknown: make(map[string]time.Time),
   }
 }
 
@@ -74,47 +95,126 @@ func jwkToRSA(jwk *jwkt) (*rsa.PublicKey, error) {
   return pub, nil
 }
 
-func (c *jwksCache) Fetch(ctx context.Context) error {
-  var jwks jwkst
-  res, err := c.httpc.GET(
-    ctx, ureq.URL("/.well-known/jwks.json"), ureq.ResJSON(&jwks),
-  )
+func (c *jwksCache) fetch(ctx context.Context) error {
+  observe(EventJWKSFetch, "", 0)
+  err := c.fetchKeys(ctx)
   if err != nil {
-    return err
+    observe(EventJWKSFetchFailure, "", 0)
   }
-  if res.StatusCode != http.StatusOK {
-    return fmt.Errorf(
-      "JWKS fetch: expected %d, got %d", http.StatusOK, res.StatusCode,
-    )
-  }
-  keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+  return err
+}
+
+func jwksKeys(jwks *jwkst) map[jwkID]any {
+  keys := make(map[jwkID]any, len(jwks.Keys))
   for _, jwk := range jwks.Keys {
-    if jwk.Kty != "RSA" {
+    alg, pub, err := parseJWK(jwk)
+    if err != nil {
+      fmt.Printf("JWK parse: %s\n", err)
       continue
     }
-    pub, err := jwkToRSA(jwk)
-    if err != nil {
-      fmt.Printf("JWK to RSA: %s\n", err)
+    if len(jwk.Alg) > 0 && jwk.Alg != alg {
       continue
     }
-    keys[jwk.Kid] = pub
+    keys[jwkID{kid: jwk.Kid, alg: alg}] = pub
+  }
+  return keys
+}
+
+func (c *jwksCache) fetchHTTP(
+  ctx context.Context,
+) (map[jwkID]any, time.Duration, error) {
+  var jwks jwkst
+  res, err := c.httpc.GET(ctx, ureq.URL(c.cfg.url), ureq.ResJSON(&jwks))
+  if err != nil {
+    return nil, 0, err
+  }
+  if res.StatusCode != http.StatusOK {
+    return nil, 0, fmt.Errorf(
+      "JWKS fetch: expected %d, got %d", http.StatusOK, res.StatusCode,
+    )
+  }
+  maxAge := cacheMaxAge(res.Header.Get("Cache-Control"))
+  return jwksKeys(&jwks), maxAge, nil
+}
+
+func (c *jwksCache) fetchKeys(ctx context.Context) error {
+  var keys map[jwkID]any
+  var maxAge time.Duration
+  var err error
+  if c.cfg.source != nil {
+    keys, err = c.cfg.source(ctx)
+  } else {
+    keys, maxAge, err = c.fetchHTTP(ctx)
+  }
+  if err != nil {
+    return err
   }
   if len(keys) == 0 {
     return errors.New("JWKS fetch: empty key set")
   }
   c.mtx.Lock()
   defer c.mtx.Unlock()
+  if len(c.keys) > 0 {
+    for id := range keys {
+      _, exist := c.keys[id]
+      if !exist {
+        observe(EventJWKSRotation, "", 0)
+        break
+      }
+    }
+  }
   c.keys = keys
+  c.maxAge = maxAge
   return nil
 }
 
 func (c *jwksCache) Key(kid string) (*rsa.PublicKey, bool) {
+  pub, exist := c.KeyFor(kid, AlgRS256)
+  if !exist {
+    return nil, false
+  }
+  return pub.(*rsa.PublicKey), true
+}
+
+// *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+func (c *jwksCache) KeyFor(kid, alg string) (any, bool) {
   c.mtx.RLock()
   defer c.mtx.RUnlock()
-  pub, exist := c.keys[kid]
+  pub, exist := c.keys[jwkID{kid: kid, alg: alg}]
   return pub, exist
 }
 
+func (c *jwksCache) lookup(
+  ctx context.Context, kid, alg string,
+) (any, error) {
+  pub, exist := c.KeyFor(kid, alg)
+  if exist {
+    return pub, nil
+  }
+  id := kid + "|" + alg
+  if !c.refetchAllowed(id) {
+    return nil, ErrUnknownKid
+  }
+  // Re-fetch Cognito-rotate JWKS
+  err := c.Fetch(ctx)
+  if err != nil {
+    return nil, userv.Unautorized(err.Error())
+  }
+  pub, exist = c.KeyFor(kid, alg)
+  if !exist {
+    c.markUnknown(id)
+    return nil, ErrUnknownKid
+  }
+  return pub, nil
+}
+
+// Key function backed by the JWKS for JWTVerify
+func (c *jwksCache) KeyFunc() KeyFunc {
+  return func(ctx context.Context, alg, kid string) (any, error) {
+    return c.lookup(ctx, kid, alg)
+  }
+}
+
 const (
   TokenUseAccess = "access"
   TokenUseID = "id"
@@ -129,30 +229,69 @@ type jwtHeader struct {
 type JWTClaims struct {
   // Access token
   Iss string `json:"iss"`
+  Sub string `json:"sub"`
   TokenUse string `json:"token_use"`
   Exp int64 `json:"exp"`
+  Nbf int64 `json:"nbf"`
+  Iat int64 `json:"iat"`
   ClientID string `json:"client_id"`
   Roles []string `json:"cognito:groups"`
-  // ID token
-  Aud string `json:"aud"`
+  Scope string `json:"scope"`
+  // ID token, Aud is set for a single audience
+  Aud string `json:"-"`
+  Auds []string `json:"-"`
+  Azp string `json:"azp"`
   Email string `json:"email"`
+  // All claims
+  raw map[string]any
+}
+
+func decodeClaims(jclaims []byte) (*JWTClaims, error) {
+  var claims JWTClaims
+  err := json.Unmarshal(jclaims, &claims)
+  if err != nil {
+    return nil, err
+  }
+  err = json.Unmarshal(jclaims, &claims.raw)
+  if err != nil {
+    return nil, err
+  }
+  // The aud claim is a string or an array
+  switch aud := claims.raw["aud"].(type) {
+  case string:
+    claims.Aud, claims.Auds = aud, []string{aud}
+  case []any:
+    for _, item := range aud {
+      str, ok := item.(string)
+      if ok {
+        claims.Auds = append(claims.Auds, str)
+      }
+    }
+    if len(claims.Auds) == 1 {
+      claims.Aud = claims.Auds[0]
+    }
+  }
+  return &claims, nil
 }
 
 func jwtClaimsCheck(
-  claims *JWTClaims, issuer, tokenUse string,
-  clientIDs []string, roles [][]string,
+  claims *JWTClaims, issuer, tokenUse string, clientIDs []string,
 ) error {
   // JWT issuer
   if claims.Iss != issuer {
-    return userv.Unautorized("invalid JWT issuer")
+    return ErrInvalidIssuer
   }
   // JWT use
   if claims.TokenUse != tokenUse {
     return userv.Unautorized("invalid JWT use")
   }
-  // JWT expiry
-  if time.Unix(claims.Exp, 0).UTC().Before(time.Now().UTC()) {
-    return userv.Unautorized("expired JWT")
+  // JWT expiry, not before and issued at
+  if claims.Exp == 0 {
+    return ErrExpired
+  }
+  err := timeClaimsCheck(claims)
+  if err != nil {
+    return err
   }
   // JWT client ID
   switch tokenUse {
@@ -167,80 +306,81 @@ func jwtClaimsCheck(
   default:
     return userv.Unautorized("invalid token use")
   }
-  // JWT roles [||] && [||]
-  for _, query := range roles {
-    found := ucheck.ContainsAny(claims.Roles, query)
-    if found == nil {
-      err := fmt.Errorf(
-        "missing role: at least one of %s is required",
-        strings.Join(query, ", "),
-      )
-      return userv.Forbidden(err.Error())
-    }
-  }
   return nil
 }
 
-func JWTRS256Assert(
+func JWTRS256Verify(
   ctx context.Context, jwt string, jwks *jwksCache, issuer, tokenUse string,
-  clientIDs []string, roles [][]string, // [||] && [||]
-) error {
-  // Parse JWT
-  parts := strings.Split(jwt, ".")
-  if len(parts) != 3 {
-    return userv.Unautorized("invalid JWT format")
-  }
-  ehead, eclaims, esig := parts[0], parts[1], parts[2]
-  // Check JWT RS256 signature algorithm
-  jhead, err := base64.RawURLEncoding.DecodeString(ehead)
-  if err != nil {
-    return userv.Unautorized("invalid JWT header encoding")
-  }
-  var head jwtHeader
-  err = json.Unmarshal(jhead, &head)
+  clientIDs []string,
+) (claims *JWTClaims, err error) {
+  defer func(start time.Time) {
+    observeVerify(start, err)
+  }(time.Now())
+  return jwtRS256Verify(ctx, jwt, jwks, issuer, tokenUse, clientIDs)
+}
+
+func jwtRS256Verify(
+  ctx context.Context, jwt string, jwks *jwksCache, issuer, tokenUse string,
+  clientIDs []string,
+) (*JWTClaims, error) {
+  p, err := parseJWT(jwt)
   if err != nil {
-    return userv.Unautorized("invalid JWT header format")
+    return nil, err
   }
-  if head.Alg != "RS256" {
-    return userv.Unautorized("unsupported JWT signature algorithm")
+  // Check JWT RS256 signature algorithm
+  if p.head.Alg != AlgRS256 {
+    return nil, userv.Unautorized("unsupported JWT signature algorithm")
   }
   // Lookup verifying JWK
-  pub, exist := jwks.Key(head.Kid)
-  if !exist {
-    // Re-fetch Cognito-rotate JWKS
-    err = jwks.Fetch(ctx)
-    if err != nil {
-      return userv.Unautorized(err.Error())
-    }
-    pub, exist = jwks.Key(head.Kid)
-    if !exist {
-      return userv.Unautorized("JWKS kid is not found")
-    }
+  pub, err := jwks.lookup(ctx, p.head.Kid, AlgRS256)
+  if err != nil {
+    return nil, err
   }
   // Verify JWT RS256 signature
-  msg := fmt.Sprintf("%s.%s", ehead, eclaims)
-  h := sha256.New()
-  h.Write([]byte(msg))
-  hash := h.Sum(nil)
-  sig, err := base64.RawURLEncoding.DecodeString(esig)
+  err = verifySignature(AlgRS256, pub, p.msg, p.sig)
   if err != nil {
-    return userv.Unautorized("invalid JWT signature format")
+    return nil, err
   }
-  err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash, sig)
+  // Check JWT claims
+  claims, err := decodeClaims(p.claims)
   if err != nil {
-    return userv.Unautorized("invalid JWT signature")
+    return nil, userv.Unautorized("invalid JWT claims format")
   }
-  // Check JWT claims
-  jclaims, err := base64.RawURLEncoding.DecodeString(eclaims)
+  err = jwtClaimsCheck(claims, issuer, tokenUse, clientIDs)
   if err != nil {
-    return userv.Unautorized("invalid JWT claims encoding")
+    return nil, err
   }
-  var claims JWTClaims
-  err = json.Unmarshal(jclaims, &claims)
+  return claims, nil
+}
+
+func JWTRS256AssertPolicy(
+  ctx context.Context, jwt string, jwks *jwksCache, issuer, tokenUse string,
+  clientIDs []string, policy Policy,
+) error {
+  claims, err := JWTRS256Verify(ctx, jwt, jwks, issuer, tokenUse, clientIDs)
   if err != nil {
-    return userv.Unautorized("invalid JWT claims format")
+    return err
   }
-  return jwtClaimsCheck(&claims, issuer, tokenUse, clientIDs, roles)
+  return policy(claims)
+}
+
+func JWTRS256Assert(
+  ctx context.Context, jwt string, jwks *jwksCache, issuer, tokenUse string,
+  clientIDs []string, roles [][]string, // [||] && [||]
+) error {
+  return JWTRS256AssertPolicy(
+    ctx, jwt, jwks, issuer, tokenUse, clientIDs, Roles(roles),
+  )
+}
+
+func JWTRS256AssertScopes(
+  ctx context.Context, jwt string, jwks *jwksCache, issuer, tokenUse string,
+  clientIDs []string, roles, scopes [][]string, // [||] && [||]
+) error {
+  return JWTRS256AssertPolicy(
+    ctx, jwt, jwks, issuer, tokenUse, clientIDs,
+    RequireAll(Roles(roles), Scopes(scopes)),
+  )
 }
 
 func JWTDecodeClaims(jwt string) (*JWTClaims, error) {
@@ -252,12 +392,7 @@ func JWTDecodeClaims(jwt string) (*JWTClaims, error) {
   if err != nil {
     return nil, err
   }
-  var claims JWTClaims
-  err = json.Unmarshal([]byte(jstr), &claims)
-  if err != nil {
-    return nil, err
-  }
-  return &claims, nil
+  return decodeClaims(jstr)
 }
 
 func JWTDecode(jwt string) (map[string]any, error) {
@@ -293,3 +428,97 @@ func MakeJWTPass() func(roles [][]string) userv.Middleware {
     }
   }
 }
+
+type authConfig struct {
+  extractors []Extractor
+  revoker Revoker
+}
+
+type authOption func(cfg *authConfig)
+
+// The extractors e.g. FromCookie replace the Authorization header
+func AuthExtractors(extractors ...Extractor) authOption {
+  return func(cfg *authConfig) {
+    cfg.extractors = append(cfg.extractors, extractors...)
+  }
+}
+
+// Revoked and signed out tokens are rejected after verification
+func AuthRevoker(revoker Revoker) authOption {
+  return func(cfg *authConfig) {
+    cfg.revoker = revoker
+  }
+}
+
+func jwtAuth(
+  jwks *jwksCache, issuer string, clientIDs []string, policy Policy,
+  opts []authOption,
+) userv.Middleware {
+  cfg := &authConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      jwt, found := extractToken(r, cfg.extractors)
+      if !found {
+        userv.WriteError(w, userv.Unautorized("missing JWT"))
+        return
+      }
+      ctx := r.Context()
+      claims, err := JWTRS256Verify(
+        ctx, jwt, jwks, issuer, TokenUseAccess, clientIDs,
+      )
+      if err != nil {
+        userv.WriteError(w, err)
+        return
+      }
+      if cfg.revoker != nil {
+        err = revokedCheck(ctx, cfg.revoker, jwt, claims)
+        if err != nil {
+          userv.WriteError(w, err)
+          return
+        }
+      }
+      err = policy(claims)
+      if err != nil {
+        userv.WriteError(w, err)
+        return
+      }
+      ctx = WithClaims(ctx, claims)
+      ctx = userv.WithPrincipal(ctx, claims.Sub)
+      next(w, r.WithContext(ctx))
+    }
+  }
+}
+
+// Verifies the Bearer access token, checks roles [||] && [||], stores the
+// claims in the request context and the subject as the userv principal e.g.
+// JWTAuth(jwks, issuer, clientIDs, AuthRevoker(revoker))
+func JWTAuth(
+  jwks *jwksCache, issuer string, clientIDs []string, opts ...authOption,
+) func(roles [][]string) userv.Middleware {
+  return func(roles [][]string) userv.Middleware {
+    return jwtAuth(jwks, issuer, clientIDs, Roles(roles), opts)
+  }
+}
+
+// Same as JWTAuth with any policy e.g. Permissions.Policy("invoice:write")
+func JWTPolicyAuth(
+  jwks *jwksCache, issuer string, clientIDs []string, opts ...authOption,
+) func(policy Policy) userv.Middleware {
+  return func(policy Policy) userv.Middleware {
+    return jwtAuth(jwks, issuer, clientIDs, policy, opts)
+  }
+}
+
+// Same as JWTAuth with the space-separated scope claim checked [||] && [||]
+func JWTScopeAuth(
+  jwks *jwksCache, issuer string, clientIDs []string, opts ...authOption,
+) func(roles, scopes [][]string) userv.Middleware {
+  return func(roles, scopes [][]string) userv.Middleware {
+    return jwtAuth(
+      jwks, issuer, clientIDs, RequireAll(Roles(roles), Scopes(scopes)), opts,
+    )
+  }
+}
@@ -0,0 +1,53 @@
+package ujwt
+
+import (
+	"context"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+type claimsKey struct{}
+
+func WithClaims(ctx context.Context, claims *JWTClaims) context.Context {
+  return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFrom(ctx context.Context) (*JWTClaims, bool) {
+  claims, exist := ctx.Value(claimsKey{}).(*JWTClaims)
+  return claims, exist && claims != nil
+}
+
+func claimsRequired(ctx context.Context) (*JWTClaims, error) {
+  claims, exist := ClaimsFrom(ctx)
+  if !exist {
+    return nil, userv.Unautorized("missing JWT claims")
+  }
+  return claims, nil
+}
+
+func Subject(ctx context.Context) (string, error) {
+  claims, err := claimsRequired(ctx)
+  if err != nil {
+    return "", err
+  }
+  if len(claims.Sub) == 0 {
+    return "", userv.Unautorized("missing JWT subject")
+  }
+  return claims.Sub, nil
+}
+
+func RequirePolicy(ctx context.Context, policy Policy) error {
+  claims, err := claimsRequired(ctx)
+  if err != nil {
+    return err
+  }
+  return policy(claims)
+}
+
+func RequireRole(ctx context.Context, roles ...string) error { // [||]
+  return RequirePolicy(ctx, AnyRole(roles...))
+}
+
+func RequireScope(ctx context.Context, scope string) error {
+  return RequirePolicy(ctx, Scope(scope))
+}
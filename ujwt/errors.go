@@ -0,0 +1,140 @@
+package ujwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Verification failures map to 401 and policy failures to 403, use errors.Is
+// and errors.As to tell the causes apart
+var (
+  ErrExpired error = userv.Unautorized("expired JWT")
+  ErrNotYetValid error = userv.Unautorized("JWT is not valid yet")
+  ErrInvalidSignature error = userv.Unautorized("invalid JWT signature")
+  ErrUnknownKid error = userv.Unautorized("JWKS kid is not found")
+  ErrInvalidIssuer error = userv.Unautorized("invalid JWT issuer")
+  ErrInvalidAudience error = userv.Unautorized("invalid JWT audience")
+  ErrUnknownIssuer error = userv.Unautorized("unknown JWT issuer")
+  ErrRevoked error = userv.Unautorized("revoked JWT")
+  ErrReplayed error = userv.Unautorized("replayed JWT")
+  ErrAlgNotAllowed error = userv.Unautorized(
+    "JWT signature algorithm is not allowed",
+  )
+  ErrKeyNotPinned error = userv.Unautorized("JWT key is not pinned")
+  ErrTooOld error = userv.Unautorized("JWT is too old")
+  ErrDecryption error = userv.Unautorized("JWE decryption failed")
+)
+
+// Any of the roles is required, a single role is required
+type ErrMissingRole struct {
+  Roles []string
+}
+
+func (e ErrMissingRole) Error() string {
+  if len(e.Roles) == 1 {
+    return fmt.Sprintf("missing role: %s is required", e.Roles[0])
+  }
+  return fmt.Sprintf(
+    "missing role: at least one of %s is required", strings.Join(e.Roles, ", "),
+  )
+}
+
+func (e ErrMissingRole) Unwrap() error {
+  return userv.Forbidden(e.Error())
+}
+
+type ErrMissingScope struct {
+  Scopes []string
+}
+
+func (e ErrMissingScope) Error() string {
+  if len(e.Scopes) == 1 {
+    return fmt.Sprintf("missing scope: %s is required", e.Scopes[0])
+  }
+  return fmt.Sprintf(
+    "missing scope: at least one of %s is required",
+    strings.Join(e.Scopes, ", "),
+  )
+}
+
+func (e ErrMissingScope) Unwrap() error {
+  return userv.Forbidden(e.Error())
+}
+
+type ErrMissingClaim struct {
+  Claim string
+}
+
+func (e ErrMissingClaim) Error() string {
+  return fmt.Sprintf("missing JWT claim %s", e.Claim)
+}
+
+func (e ErrMissingClaim) Unwrap() error {
+  return userv.Unautorized(e.Error())
+}
+
+// Combined policy failures keep the individual errors for errors.As
+type policyError struct {
+  msg string
+  errs []error
+}
+
+func (e *policyError) Error() string {
+  return e.msg
+}
+
+func (e *policyError) Unwrap() []error {
+  return append([]error{userv.Forbidden(e.msg)}, e.errs...)
+}
+
+// Low-cardinality failure reason for metrics
+func Reason(err error) string {
+  var missingRole ErrMissingRole
+  var missingScope ErrMissingScope
+  var missingClaim ErrMissingClaim
+  var missingPermission ErrMissingPermission
+  switch {
+  case err == nil:
+    return ""
+  case errors.Is(err, ErrExpired):
+    return "expired"
+  case errors.Is(err, ErrNotYetValid):
+    return "not_yet_valid"
+  case errors.Is(err, ErrInvalidSignature):
+    return "invalid_signature"
+  case errors.Is(err, ErrUnknownKid):
+    return "unknown_kid"
+  case errors.Is(err, ErrInvalidIssuer), errors.Is(err, ErrUnknownIssuer):
+    return "invalid_issuer"
+  case errors.Is(err, ErrInvalidAudience):
+    return "invalid_audience"
+  case errors.Is(err, ErrRevoked):
+    return "revoked"
+  case errors.Is(err, ErrReplayed):
+    return "replayed"
+  case errors.Is(err, ErrAlgNotAllowed):
+    return "alg_not_allowed"
+  case errors.Is(err, ErrKeyNotPinned):
+    return "key_not_pinned"
+  case errors.Is(err, ErrTooOld):
+    return "too_old"
+  case errors.Is(err, ErrDecryption):
+    return "decryption"
+  case errors.As(err, &missingRole):
+    return "missing_role"
+  case errors.As(err, &missingScope):
+    return "missing_scope"
+  case errors.As(err, &missingClaim):
+    return "missing_claim"
+  case errors.As(err, &missingPermission):
+    return "missing_permission"
+  }
+  var forbidden userv.Forbidden
+  if errors.As(err, &forbidden) {
+    return "forbidden"
+  }
+  return "invalid"
+}
@@ -0,0 +1,53 @@
+package ujwt
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+)
+
+// Returns the raw token from the request, custom functions are extractors too
+type Extractor func(r *http.Request) (string, bool)
+
+// Authorization: Bearer <token>
+func FromHeader() Extractor {
+  return func(r *http.Request) (string, bool) {
+    authz := r.Header.Get(ureq.AuthZHeader)
+    jwt, found := strings.CutPrefix(authz, ureq.AuthZBearer)
+    return jwt, found && len(jwt) > 0
+  }
+}
+
+func FromCookie(name string) Extractor {
+  return func(r *http.Request) (string, bool) {
+    cookie, err := r.Cookie(name)
+    if err != nil || len(cookie.Value) == 0 {
+      return "", false
+    }
+    return cookie.Value, true
+  }
+}
+
+// For SSE and WebSocket endpoints where browsers cannot set headers. Query
+// strings end up in access logs, so prefer short-lived tokens
+func FromQuery(name string) Extractor {
+  return func(r *http.Request) (string, bool) {
+    jwt := r.URL.Query().Get(name)
+    return jwt, len(jwt) > 0
+  }
+}
+
+// The first extractor that finds a token wins, the header by default
+func extractToken(r *http.Request, extractors []Extractor) (string, bool) {
+  if len(extractors) == 0 {
+    extractors = []Extractor{FromHeader()}
+  }
+  for _, extract := range extractors {
+    jwt, found := extract(r)
+    if found {
+      return jwt, true
+    }
+  }
+  return "", false
+}
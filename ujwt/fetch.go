@@ -0,0 +1,93 @@
+package ujwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+type jwksConfig struct {
+  url string
+  minRefetch time.Duration
+  unknownTTL time.Duration
+  source keySource
+}
+
+type jwksOption func(cfg *jwksConfig)
+
+// JWKS path relative to the client base URL or an absolute jwks_uri
+func JWKSURL(url string) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.url = url
+  }
+}
+
+// Minimum interval between JWKS re-fetches triggered by unknown kids
+func JWKSMinRefetch(interval time.Duration) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.minRefetch = interval
+  }
+}
+
+// How long an unknown kid is rejected without a JWKS re-fetch
+func JWKSUnknownTTL(ttl time.Duration) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.unknownTTL = ttl
+  }
+}
+
+type jwksFetch struct {
+  done chan struct{}
+  err error
+}
+
+// Concurrent fetches share a single JWKS request
+func (c *jwksCache) Fetch(ctx context.Context) error {
+  c.fetchMtx.Lock()
+  call := c.fetching
+  if call == nil {
+    call = &jwksFetch{done: make(chan struct{})}
+    c.fetching = call
+    c.fetchMtx.Unlock()
+    call.err = c.fetch(context.WithoutCancel(ctx))
+    c.fetchMtx.Lock()
+    c.fetching = nil
+    c.fetched = time.Now()
+    c.fetchMtx.Unlock()
+    close(call.done)
+    return call.err
+  }
+  c.fetchMtx.Unlock()
+  select {
+  case <-call.done:
+    return call.err
+  case <-ctx.Done():
+    return ctx.Err()
+  }
+}
+
+// Unknown kids are negatively cached and re-fetches are throttled so tokens
+// with random kids cannot hammer the JWKS endpoint
+func (c *jwksCache) refetchAllowed(kid string) bool {
+  c.fetchMtx.Lock()
+  defer c.fetchMtx.Unlock()
+  now := time.Now()
+  expiry, exist := c.unknown[kid]
+  if exist && now.Before(expiry) {
+    return false
+  }
+  if c.fetching != nil {
+    return true
+  }
+  return now.Sub(c.fetched) >= c.cfg.minRefetch
+}
+
+func (c *jwksCache) markUnknown(kid string) {
+  c.fetchMtx.Lock()
+  defer c.fetchMtx.Unlock()
+  userv.EvictExpired(&c.evictor, c.unknown, func(expiry time.Time) time.Time {
+    return expiry
+  })
+  c.unknown[kid] = time.Now().Add(c.cfg.unknownTTL)
+}
@@ -0,0 +1,160 @@
+package ujwt
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/ucheck"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Dotted path into nested claims e.g. realm_access.roles
+func claimPath(raw map[string]any, path string) any {
+  var val any = raw
+  for name := range strings.SplitSeq(path, ".") {
+    obj, ok := val.(map[string]any)
+    if !ok {
+      return nil
+    }
+    val = obj[name]
+  }
+  return val
+}
+
+func claimStrings(val any) []string {
+  switch v := val.(type) {
+  case string:
+    return strings.Fields(v)
+  case []any:
+    strs := make([]string, 0, len(v))
+    for _, item := range v {
+      str, ok := item.(string)
+      if ok {
+        strs = append(strs, str)
+      }
+    }
+    return strs
+  }
+  return nil
+}
+
+type assertConfig struct {
+  issuer string
+  audiences []string
+  azps []string
+  rolesPath string
+  policy Policy
+}
+
+type assertOption func(cfg *assertConfig)
+
+func AssertIssuer(issuer string) assertOption {
+  return func(cfg *assertConfig) {
+    cfg.issuer = issuer
+  }
+}
+
+// The aud claim, a string or an array, must contain one of the audiences
+func AssertAudience(audiences ...string) assertOption {
+  return func(cfg *assertConfig) {
+    cfg.audiences = audiences
+  }
+}
+
+// The azp claim must be one of the clients, it is required for tokens with
+// several audiences
+func AssertAzp(clientIDs ...string) assertOption {
+  return func(cfg *assertConfig) {
+    cfg.azps = clientIDs
+  }
+}
+
+// Roles claim path instead of cognito:groups e.g. realm_access.roles
+func AssertRolesPath(path string) assertOption {
+  return func(cfg *assertConfig) {
+    cfg.rolesPath = path
+  }
+}
+
+func AssertPolicy(policy Policy) assertOption {
+  return func(cfg *assertConfig) {
+    cfg.policy = policy
+  }
+}
+
+func assertClaims(claims *JWTClaims, cfg *assertConfig) error {
+  if len(cfg.issuer) > 0 && claims.Iss != cfg.issuer {
+    return ErrInvalidIssuer
+  }
+  if len(cfg.audiences) > 0 &&
+    ucheck.ContainsAny(claims.Auds, cfg.audiences) == nil {
+    return ErrInvalidAudience
+  }
+  if len(cfg.azps) > 0 {
+    if len(claims.Azp) == 0 && len(claims.Auds) > 1 {
+      return ErrMissingClaim{Claim: "azp"}
+    }
+    if len(claims.Azp) > 0 && !slices.Contains(cfg.azps, claims.Azp) {
+      return ErrInvalidAudience
+    }
+    if len(claims.Azp) == 0 && !slices.Contains(cfg.azps, claims.Aud) {
+      return ErrInvalidAudience
+    }
+  }
+  if len(cfg.rolesPath) > 0 {
+    claims.Roles = claimStrings(claimPath(claims.raw, cfg.rolesPath))
+  }
+  if cfg.policy != nil {
+    return cfg.policy(claims)
+  }
+  return nil
+}
+
+func claimsAs[T any](claims *JWTClaims) (*T, error) {
+  jclaims, err := json.Marshal(claims.raw)
+  if err != nil {
+    return nil, err
+  }
+  var val T
+  err = json.Unmarshal(jclaims, &val)
+  if err != nil {
+    return nil, err
+  }
+  return &val, nil
+}
+
+// Verifies the signature, expiry and the configured standard claims, then
+// decodes the payload into the caller struct
+func JWTAssert[T any](
+  ctx context.Context, jwt string, keyFunc KeyFunc, algs []string,
+  opts ...assertOption,
+) (*T, error) {
+  cfg := &assertConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  claims, err := JWTVerify(ctx, jwt, keyFunc, algs...)
+  if err != nil {
+    return nil, err
+  }
+  err = assertClaims(claims, cfg)
+  if err != nil {
+    return nil, err
+  }
+  val, err := claimsAs[T](claims)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT claims format")
+  }
+  return val, nil
+}
+
+// Decodes the payload without verification
+func JWTDecodeClaimsAs[T any](jwt string) (*T, error) {
+  claims, err := JWTDecodeClaims(jwt)
+  if err != nil {
+    return nil, err
+  }
+  return claimsAs[T](claims)
+}
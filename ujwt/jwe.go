@@ -0,0 +1,155 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+const (
+  AlgRSAOAEP = "RSA-OAEP"
+  AlgRSAOAEP256 = "RSA-OAEP-256"
+  EncA128GCM = "A128GCM"
+  EncA256GCM = "A256GCM"
+)
+
+type jweHeader struct {
+  Alg string `json:"alg"`
+  Enc string `json:"enc"`
+  Kid string `json:"kid,omitempty"`
+  Cty string `json:"cty,omitempty"`
+}
+
+// Compact JWE has five parts, compact JWS has three
+func IsJWE(token string) bool {
+  return strings.Count(token, ".") == 4
+}
+
+func oaepHash(alg string) (hash.Hash, bool) {
+  switch alg {
+  case AlgRSAOAEP:
+    return sha1.New(), true
+  case AlgRSAOAEP256:
+    return sha256.New(), true
+  }
+  return nil, false
+}
+
+func cekSize(enc string) (int, bool) {
+  switch enc {
+  case EncA128GCM:
+    return 16, true
+  case EncA256GCM:
+    return 32, true
+  }
+  return 0, false
+}
+
+// Decrypts a compact JWE with RSA-OAEP or RSA-OAEP-256 key wrapping and
+// A128GCM or A256GCM content encryption, returns the payload, usually a
+// nested JWS
+func JWEDecrypt(jwe string, key *rsa.PrivateKey) ([]byte, error) {
+  parts := strings.Split(jwe, ".")
+  if len(parts) != 5 {
+    return nil, userv.Unautorized("invalid JWE format")
+  }
+  jhead, err := base64.RawURLEncoding.DecodeString(parts[0])
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWE header encoding")
+  }
+  var head jweHeader
+  err = json.Unmarshal(jhead, &head)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWE header format")
+  }
+  hash, ok := oaepHash(head.Alg)
+  if !ok {
+    return nil, ErrAlgNotAllowed
+  }
+  size, ok := cekSize(head.Enc)
+  if !ok {
+    return nil, userv.Unautorized("unsupported JWE content encryption")
+  }
+  var segs [4][]byte
+  for i, part := range parts[1:] {
+    segs[i], err = base64.RawURLEncoding.DecodeString(part)
+    if err != nil {
+      return nil, userv.Unautorized("invalid JWE encoding")
+    }
+  }
+  ekey, iv, ciphertext, tag := segs[0], segs[1], segs[2], segs[3]
+  cek, err := rsa.DecryptOAEP(hash, nil, key, ekey, nil)
+  if err != nil || len(cek) != size {
+    return nil, ErrDecryption
+  }
+  block, err := aes.NewCipher(cek)
+  if err != nil {
+    return nil, ErrDecryption
+  }
+  gcm, err := cipher.NewGCM(block)
+  if err != nil || len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+    return nil, ErrDecryption
+  }
+  aad := []byte(parts[0])
+  payload, err := gcm.Open(nil, iv, append(ciphertext, tag...), aad)
+  if err != nil {
+    return nil, ErrDecryption
+  }
+  return payload, nil
+}
+
+// Encrypts a JWS with RSA-OAEP-256 and A256GCM e.g. for tests
+func JWEEncrypt(jwt string, pub *rsa.PublicKey, kid string) (string, error) {
+  head := jweHeader{Alg: AlgRSAOAEP256, Enc: EncA256GCM, Kid: kid, Cty: "JWT"}
+  jhead, err := json.Marshal(head)
+  if err != nil {
+    return "", err
+  }
+  cek := make([]byte, 32)
+  _, _ = rand.Read(cek)
+  ekey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil)
+  if err != nil {
+    return "", err
+  }
+  block, err := aes.NewCipher(cek)
+  if err != nil {
+    return "", err
+  }
+  gcm, err := cipher.NewGCM(block)
+  if err != nil {
+    return "", err
+  }
+  iv := make([]byte, gcm.NonceSize())
+  _, _ = rand.Read(iv)
+  ehead := base64.RawURLEncoding.EncodeToString(jhead)
+  sealed := gcm.Seal(nil, iv, []byte(jwt), []byte(ehead))
+  split := len(sealed) - gcm.Overhead()
+  enc := base64.RawURLEncoding.EncodeToString
+  return strings.Join([]string{
+    ehead, enc(ekey), enc(iv), enc(sealed[:split]), enc(sealed[split:]),
+  }, "."), nil
+}
+
+// Decrypts JWE tokens before verification, passes JWS tokens through
+func DecryptingVerify(verify VerifyFunc, key *rsa.PrivateKey) VerifyFunc {
+  return func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    if IsJWE(jwt) {
+      payload, err := JWEDecrypt(jwt, key)
+      if err != nil {
+        return nil, err
+      }
+      jwt = string(payload)
+    }
+    return verify(ctx, jwt)
+  }
+}
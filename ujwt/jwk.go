@@ -0,0 +1,75 @@
+package ujwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type jwkID struct {
+  kid string
+  alg string
+}
+
+func jwkToEC(jwk *jwkt) (*ecdsa.PublicKey, string, error) {
+  var curve elliptic.Curve
+  var alg string
+  switch jwk.Crv {
+  case "P-256":
+    curve, alg = elliptic.P256(), AlgES256
+  case "P-384":
+    curve, alg = elliptic.P384(), AlgES384
+  default:
+    return nil, "", fmt.Errorf("unsupported JWK curve %s", jwk.Crv)
+  }
+  xb, err := base64.RawURLEncoding.DecodeString(jwk.X)
+  if err != nil {
+    return nil, "", err
+  }
+  yb, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+  if err != nil {
+    return nil, "", err
+  }
+  pub := &ecdsa.PublicKey{
+    Curve: curve, X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb),
+  }
+  if !curve.IsOnCurve(pub.X, pub.Y) {
+    return nil, "", errors.New("JWK point is not on the curve")
+  }
+  return pub, alg, nil
+}
+
+func jwkToOKP(jwk *jwkt) (ed25519.PublicKey, error) {
+  if jwk.Crv != "Ed25519" {
+    return nil, fmt.Errorf("unsupported JWK curve %s", jwk.Crv)
+  }
+  xb, err := base64.RawURLEncoding.DecodeString(jwk.X)
+  if err != nil {
+    return nil, err
+  }
+  if len(xb) != ed25519.PublicKeySize {
+    return nil, errors.New("invalid Ed25519 JWK size")
+  }
+  return ed25519.PublicKey(xb), nil
+}
+
+// The algorithm is derived from the key type when the JWK has no alg
+func parseJWK(jwk *jwkt) (string, any, error) {
+  switch jwk.Kty {
+  case "RSA":
+    pub, err := jwkToRSA(jwk)
+    return AlgRS256, pub, err
+  case "EC":
+    pub, alg, err := jwkToEC(jwk)
+    return alg, pub, err
+  case "OKP":
+    pub, err := jwkToOKP(jwk)
+    return AlgEdDSA, pub, err
+  default:
+    return "", nil, fmt.Errorf("unsupported JWK type %s", jwk.Kty)
+  }
+}
@@ -0,0 +1,111 @@
+// Test Cognito-like issuer serving a JWKS over httptest and minting RS256
+// tokens for integration tests of JWTRS256Verify, JWTRS256Assert and JWTAuth
+package jwttest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ujwt"
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+)
+
+type Issuer struct {
+  Key *rsa.PrivateKey
+  Kid string
+  URL string
+  ClientID string
+  signer *ujwt.Signer
+}
+
+// Generates an RSA key and serves its JWKS until the test ends
+func NewIssuer(t testing.TB, clientID string) *Issuer {
+  t.Helper()
+  key, err := rsa.GenerateKey(rand.Reader, 2048)
+  if err != nil {
+    t.Fatal(err)
+  }
+  iss := &Issuer{Key: key, Kid: "jwttest", ClientID: clientID}
+  jwks := map[string]any{"keys": []map[string]string{{
+    "kid": iss.Kid,
+    "kty": "RSA",
+    "alg": ujwt.AlgRS256,
+    "use": "sig",
+    "n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+    "e": base64.RawURLEncoding.EncodeToString(
+      big.NewInt(int64(key.E)).Bytes(),
+    ),
+  }}}
+  mux := http.NewServeMux()
+  mux.HandleFunc(
+    "GET /.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+      w.Header().Set("Content-Type", "application/json")
+      _ = json.NewEncoder(w).Encode(jwks)
+    },
+  )
+  srv := httptest.NewServer(mux)
+  t.Cleanup(srv.Close)
+  iss.URL = srv.URL
+  iss.signer, err = ujwt.NewSigner(
+    ujwt.AlgRS256, key, ujwt.SignerKid(iss.Kid), ujwt.SignerIssuer(iss.URL),
+  )
+  if err != nil {
+    t.Fatal(err)
+  }
+  return iss
+}
+
+// For ujwt.NewJWKS(iss.Client())
+func (i *Issuer) Client() *ureq.Client {
+  return ureq.NewClient(ureq.BaseURL(i.URL))
+}
+
+func (i *Issuer) sign(
+  t testing.TB, subject string, claims, custom map[string]any,
+) string {
+  t.Helper()
+  maps.Copy(claims, custom)
+  jwt, err := i.signer.Sign(subject, claims)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return jwt
+}
+
+// Access token with cognito:groups, custom claims override the defaults e.g.
+// {"exp": time.Now().Add(-time.Minute).Unix()} for an expired token
+func (i *Issuer) AccessToken(
+  t testing.TB, subject string, groups []string, custom map[string]any,
+) string {
+  t.Helper()
+  claims := map[string]any{
+    "token_use": ujwt.TokenUseAccess,
+    "client_id": i.ClientID,
+    "cognito:groups": groups,
+  }
+  return i.sign(t, subject, claims, custom)
+}
+
+func (i *Issuer) IDToken(
+  t testing.TB, subject, email string, custom map[string]any,
+) string {
+  t.Helper()
+  claims := map[string]any{
+    "token_use": ujwt.TokenUseID,
+    "aud": i.ClientID,
+    "email": email,
+  }
+  return i.sign(t, subject, claims, custom)
+}
+
+func Expired() map[string]any {
+  return map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}
+}
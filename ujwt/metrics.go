@@ -0,0 +1,73 @@
+package ujwt
+
+import (
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+const (
+  EventJWKSFetch = "jwks_fetch"
+  EventJWKSFetchFailure = "jwks_fetch_failure"
+  EventJWKSRotation = "jwks_rotation"
+  EventVerifySuccess = "verify_success"
+  EventVerifyFailure = "verify_failure"
+)
+
+// Called with the failure Reason for verify_failure and the verification
+// latency for verify_success and verify_failure
+type MetricsHook func(event, reason string, elapsed time.Duration)
+
+var metricsHook atomic.Pointer[MetricsHook]
+
+func SetMetricsHook(hook MetricsHook) {
+  metricsHook.Store(&hook)
+}
+
+type metricsCounters struct {
+  mtx sync.Mutex
+  counts map[string]int64
+}
+
+var metrics = metricsCounters{counts: make(map[string]int64)}
+
+func observe(event, reason string, elapsed time.Duration) {
+  metrics.mtx.Lock()
+  metrics.counts[event]++
+  if len(reason) > 0 {
+    metrics.counts[event + ":" + reason]++
+  }
+  if event == EventVerifySuccess || event == EventVerifyFailure {
+    metrics.counts["verify_latency_us"] += elapsed.Microseconds()
+  }
+  metrics.mtx.Unlock()
+  hook := metricsHook.Load()
+  if hook != nil && *hook != nil {
+    (*hook)(event, reason, elapsed)
+  }
+}
+
+func observeVerify(start time.Time, err error) {
+  if err != nil {
+    observe(EventVerifyFailure, Reason(err), time.Since(start))
+    return
+  }
+  observe(EventVerifySuccess, "", time.Since(start))
+}
+
+// Cumulative counters e.g. verify_failure:expired for a metrics endpoint
+func Metrics() map[string]int64 {
+  metrics.mtx.Lock()
+  defer metrics.mtx.Unlock()
+  return maps.Clone(metrics.counts)
+}
+
+func MetricsHandler() http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    userv.WriteResponse(w, http.StatusOK, Metrics())
+  }
+}
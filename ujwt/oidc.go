@@ -0,0 +1,93 @@
+package ujwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+)
+
+type oidcDiscovery struct {
+  Issuer string `json:"issuer"`
+  JWKSURI string `json:"jwks_uri"`
+  AuthorizationEndpoint string `json:"authorization_endpoint"`
+  TokenEndpoint string `json:"token_endpoint"`
+  UserinfoEndpoint string `json:"userinfo_endpoint"`
+  Algs []string `json:"id_token_signing_alg_values_supported"`
+}
+
+type Provider struct {
+  Issuer string
+  JWKSURI string
+  AuthorizationEndpoint string
+  TokenEndpoint string
+  UserinfoEndpoint string
+  Algs []string
+  JWKS *jwksCache
+}
+
+// Configures the provider from issuer/.well-known/openid-configuration
+func NewProvider(
+  ctx context.Context, issuer string, opts ...jwksOption,
+) (*Provider, error) {
+  issuer = strings.TrimSuffix(issuer, "/")
+  httpc := ureq.NewClient()
+  var disc oidcDiscovery
+  res, err := httpc.GET(
+    ctx, ureq.URL(issuer + "/.well-known/openid-configuration"),
+    ureq.ResJSON(&disc),
+  )
+  if err != nil {
+    return nil, err
+  }
+  if res.StatusCode != http.StatusOK {
+    return nil, fmt.Errorf(
+      "OIDC discovery: expected %d, got %d", http.StatusOK, res.StatusCode,
+    )
+  }
+  if disc.Issuer != issuer {
+    return nil, fmt.Errorf(
+      "OIDC discovery: issuer mismatch %s != %s", disc.Issuer, issuer,
+    )
+  }
+  if len(disc.JWKSURI) == 0 {
+    return nil, errors.New("OIDC discovery: missing jwks_uri")
+  }
+  algs := disc.Algs
+  if len(algs) == 0 {
+    algs = []string{AlgRS256}
+  }
+  opts = append([]jwksOption{JWKSURL(disc.JWKSURI)}, opts...)
+  return &Provider{
+    Issuer: disc.Issuer,
+    JWKSURI: disc.JWKSURI,
+    AuthorizationEndpoint: disc.AuthorizationEndpoint,
+    TokenEndpoint: disc.TokenEndpoint,
+    UserinfoEndpoint: disc.UserinfoEndpoint,
+    Algs: algs,
+    JWKS: NewJWKS(httpc, opts...),
+  }, nil
+}
+
+// Verifies the token against the provider JWKS, algorithms and issuer
+func (p *Provider) Verify(
+  ctx context.Context, jwt string, opts ...assertOption,
+) (*JWTClaims, error) {
+  cfg := &assertConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  cfg.issuer = p.Issuer
+  claims, err := JWTVerify(ctx, jwt, p.JWKS.KeyFunc(), p.Algs...)
+  if err != nil {
+    return nil, err
+  }
+  err = assertClaims(claims, cfg)
+  if err != nil {
+    return nil, err
+  }
+  return claims, nil
+}
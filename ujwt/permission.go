@@ -0,0 +1,124 @@
+package ujwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Any of the permissions is required
+type ErrMissingPermission struct {
+  Permissions []string
+}
+
+func (e ErrMissingPermission) Error() string {
+  return fmt.Sprintf(
+    "missing permission: %s is required", strings.Join(e.Permissions, " | "),
+  )
+}
+
+func (e ErrMissingPermission) Unwrap() error {
+  return userv.Forbidden(e.Error())
+}
+
+// Maps cognito:groups to permissions e.g. invoice:write, invoice:* or *
+type Permissions struct {
+  mtx sync.RWMutex
+  byGroup map[string][]string
+}
+
+// {"admin": ["*"], "billing": ["invoice:*"], "support": ["invoice:read"]}
+func NewPermissions(table map[string][]string) *Permissions {
+  return &Permissions{byGroup: table}
+}
+
+func ReadPermissions(path string) (*Permissions, error) {
+  p := NewPermissions(nil)
+  err := p.Load(path)
+  if err != nil {
+    return nil, err
+  }
+  return p, nil
+}
+
+// Reloads the JSON permission table
+func (p *Permissions) Load(path string) error {
+  data, err := os.ReadFile(path)
+  if err != nil {
+    return err
+  }
+  var table map[string][]string
+  err = json.Unmarshal(data, &table)
+  if err != nil {
+    return fmt.Errorf("permissions %s: %w", path, err)
+  }
+  p.Replace(table)
+  return nil
+}
+
+func (p *Permissions) Replace(table map[string][]string) {
+  p.mtx.Lock()
+  defer p.mtx.Unlock()
+  p.byGroup = table
+}
+
+func (p *Permissions) Of(groups []string) []string {
+  p.mtx.RLock()
+  defer p.mtx.RUnlock()
+  var perms []string
+  for _, group := range groups {
+    perms = append(perms, p.byGroup[group]...)
+  }
+  slices.Sort(perms)
+  return slices.Compact(perms)
+}
+
+func permissionMatch(granted, perm string) bool {
+  if granted == "*" || granted == perm {
+    return true
+  }
+  prefix, found := strings.CutSuffix(granted, "*")
+  return found && strings.HasPrefix(perm, prefix)
+}
+
+func (p *Permissions) granted(claims *JWTClaims, perm string) bool {
+  p.mtx.RLock()
+  defer p.mtx.RUnlock()
+  for _, group := range claims.Roles {
+    for _, granted := range p.byGroup[group] {
+      if permissionMatch(granted, perm) {
+        return true
+      }
+    }
+  }
+  return false
+}
+
+// Any of the permissions, use RequireAll for all of them
+func (p *Permissions) Policy(perms ...string) Policy {
+  return func(claims *JWTClaims) error {
+    for _, perm := range perms {
+      if p.granted(claims, perm) {
+        return nil
+      }
+    }
+    return ErrMissingPermission{Permissions: perms}
+  }
+}
+
+func (p *Permissions) HasPermission(ctx context.Context, perm string) bool {
+  claims, exist := ClaimsFrom(ctx)
+  return exist && p.granted(claims, perm)
+}
+
+func (p *Permissions) RequirePermission(
+  ctx context.Context, perms ...string,
+) error {
+  return RequirePolicy(ctx, p.Policy(perms...))
+}
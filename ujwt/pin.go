@@ -0,0 +1,105 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// RFC 7638 SHA-256 JWK thumbprint of an RSA, ECDSA or Ed25519 public key
+func Thumbprint(pub any) (string, error) {
+  b64 := base64.RawURLEncoding.EncodeToString
+  var canonical string
+  switch key := pub.(type) {
+  case *rsa.PublicKey:
+    e := big.NewInt(int64(key.E)).Bytes()
+    canonical = fmt.Sprintf(
+      `{"e":"%s","kty":"RSA","n":"%s"}`, b64(e), b64(key.N.Bytes()),
+    )
+  case *ecdsa.PublicKey:
+    size := (key.Curve.Params().BitSize + 7) / 8
+    x, y := make([]byte, size), make([]byte, size)
+    key.X.FillBytes(x)
+    key.Y.FillBytes(y)
+    canonical = fmt.Sprintf(
+      `{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+      key.Curve.Params().Name, b64(x), b64(y),
+    )
+  case ed25519.PublicKey:
+    canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, b64(key))
+  default:
+    return "", fmt.Errorf("unsupported public key %T", pub)
+  }
+  hash := sha256.Sum256([]byte(canonical))
+  return b64(hash[:]), nil
+}
+
+// Verification outside the policy fails with reason-coded errors, so a
+// changed IdP configuration cannot downgrade the verification
+type VerifyPolicy struct {
+  Algs []string
+  // iat is required and checked when set
+  MaxAge time.Duration
+  Required []string
+  // Pinned kids and key thumbprints when set
+  Kids []string
+  Thumbprints []string
+}
+
+func (p *VerifyPolicy) keyFunc(keyFunc KeyFunc) KeyFunc {
+  return func(ctx context.Context, alg, kid string) (any, error) {
+    if len(p.Kids) > 0 && !slices.Contains(p.Kids, kid) {
+      return nil, ErrKeyNotPinned
+    }
+    key, err := keyFunc(ctx, alg, kid)
+    if err != nil {
+      return nil, err
+    }
+    if len(p.Thumbprints) > 0 {
+      thumb, err := Thumbprint(key)
+      if err != nil || !slices.Contains(p.Thumbprints, thumb) {
+        return nil, ErrKeyNotPinned
+      }
+    }
+    return key, nil
+  }
+}
+
+func (p *VerifyPolicy) Verify(
+  ctx context.Context, jwt string, keyFunc KeyFunc,
+) (*JWTClaims, error) {
+  if len(p.Algs) == 0 {
+    return nil, ErrAlgNotAllowed
+  }
+  claims, err := JWTVerify(ctx, jwt, p.keyFunc(keyFunc), p.Algs...)
+  if err != nil {
+    return nil, err
+  }
+  if p.MaxAge > 0 {
+    if claims.Iat == 0 {
+      return nil, ErrMissingClaim{Claim: "iat"}
+    }
+    if time.Since(time.Unix(claims.Iat, 0)) > p.MaxAge + ClockSkew() {
+      return nil, ErrTooOld
+    }
+  }
+  for _, name := range p.Required {
+    if claims.raw[name] == nil {
+      return nil, ErrMissingClaim{Claim: name}
+    }
+  }
+  return claims, nil
+}
+
+func (p *VerifyPolicy) VerifyFunc(keyFunc KeyFunc) VerifyFunc {
+  return func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    return p.Verify(ctx, jwt, keyFunc)
+  }
+}
@@ -0,0 +1,139 @@
+package ujwt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/volodymyrprokopyuk/go-util/ucheck"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+type Policy func(claims *JWTClaims) error
+
+func AnyRole(roles ...string) Policy {
+  return func(claims *JWTClaims) error {
+    found := ucheck.ContainsAny(claims.Roles, roles)
+    if found == nil {
+      return ErrMissingRole{Roles: roles}
+    }
+    return nil
+  }
+}
+
+func AllRoles(roles ...string) Policy {
+  return func(claims *JWTClaims) error {
+    missing := ucheck.ContainsAll(claims.Roles, roles)
+    if missing != nil {
+      return ErrMissingRole{Roles: []string{*missing}}
+    }
+    return nil
+  }
+}
+
+func Scope(scope string) Policy {
+  return func(claims *JWTClaims) error {
+    if !slices.Contains(strings.Fields(claims.Scope), scope) {
+      return ErrMissingScope{Scopes: []string{scope}}
+    }
+    return nil
+  }
+}
+
+func AnyScope(scopes ...string) Policy {
+  if len(scopes) == 1 {
+    return Scope(scopes[0])
+  }
+  return func(claims *JWTClaims) error {
+    found := ucheck.ContainsAny(strings.Fields(claims.Scope), scopes)
+    if found == nil {
+      return ErrMissingScope{Scopes: scopes}
+    }
+    return nil
+  }
+}
+
+func claimMatch(val any, value string) bool {
+  switch v := val.(type) {
+  case nil:
+    return false
+  case string:
+    return v == value
+  case []any:
+    for _, item := range v {
+      if claimMatch(item, value) {
+        return true
+      }
+    }
+    return false
+  default:
+    return fmt.Sprint(v) == value
+  }
+}
+
+func Claim(name, value string) Policy {
+  return func(claims *JWTClaims) error {
+    if !claimMatch(claims.raw[name], value) {
+      return userv.Forbidden(fmt.Sprintf(
+        "invalid claim: %s must be %s", name, value,
+      ))
+    }
+    return nil
+  }
+}
+
+func Roles(roles [][]string) Policy { // [||] && [||]
+  policies := make([]Policy, len(roles))
+  for i, query := range roles {
+    policies[i] = AnyRole(query...)
+  }
+  return RequireAll(policies...)
+}
+
+func Scopes(scopes [][]string) Policy { // [||] && [||]
+  policies := make([]Policy, len(scopes))
+  for i, query := range scopes {
+    policies[i] = AnyScope(query...)
+  }
+  return RequireAll(policies...)
+}
+
+func RequireAll(policies ...Policy) Policy {
+  return func(claims *JWTClaims) error {
+    var unmet []string
+    var errs []error
+    for _, policy := range policies {
+      err := policy(claims)
+      if err != nil {
+        unmet = append(unmet, err.Error())
+        errs = append(errs, err)
+      }
+    }
+    if len(unmet) > 0 {
+      return &policyError{msg: strings.Join(unmet, "; "), errs: errs}
+    }
+    return nil
+  }
+}
+
+func RequireAny(policies ...Policy) Policy {
+  return func(claims *JWTClaims) error {
+    unmet := make([]string, 0, len(policies))
+    errs := make([]error, 0, len(policies))
+    for _, policy := range policies {
+      err := policy(claims)
+      if err == nil {
+        return nil
+      }
+      unmet = append(unmet, err.Error())
+      errs = append(errs, err)
+    }
+    if len(unmet) > 0 {
+      msg := fmt.Sprintf(
+        "at least one of is required: %s", strings.Join(unmet, " | "),
+      )
+      return &policyError{msg: msg, errs: errs}
+    }
+    return nil
+  }
+}
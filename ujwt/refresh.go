@@ -0,0 +1,84 @@
+package ujwt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/urand"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+func cacheMaxAge(cacheControl string) time.Duration {
+  for directive := range strings.SplitSeq(cacheControl, ",") {
+    value, found := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+    if !found {
+      continue
+    }
+    secs, err := strconv.Atoi(value)
+    if err == nil && secs > 0 {
+      return time.Duration(secs) * time.Second
+    }
+  }
+  return 0
+}
+
+func (c *jwksCache) refreshDelay(interval time.Duration) time.Duration {
+  c.mtx.RLock()
+  maxAge := c.maxAge
+  c.mtx.RUnlock()
+  if maxAge > 0 {
+    interval = min(interval, maxAge)
+  }
+  return urand.Jitter(interval, interval / 10)
+}
+
+// Periodically re-fetches the JWKS so rotated and revoked keys are picked up
+// without a kid miss. The period is the interval or the Cache-Control max-age,
+// whichever is shorter, with 10% jitter
+func (c *jwksCache) StartRefresh(ctx context.Context, interval time.Duration) {
+  c.refreshMtx.Lock()
+  defer c.refreshMtx.Unlock()
+  if c.stop != nil {
+    return
+  }
+  ctx, c.stop = context.WithCancel(ctx)
+  c.done = make(chan struct{})
+  go func() {
+    defer close(c.done)
+    timer := time.NewTimer(c.refreshDelay(interval))
+    defer timer.Stop()
+    for {
+      select {
+      case <-ctx.Done():
+        return
+      case <-timer.C:
+        start := time.Now()
+        err := c.Fetch(ctx)
+        if err != nil {
+          userv.LogAction("refresh", err, start, "JWKS")
+        }
+        timer.Reset(c.refreshDelay(interval))
+      }
+    }
+  }()
+}
+
+// Stops the refresh and waits for it, suitable for userv.OnShutdown
+func (c *jwksCache) Stop(ctx context.Context) error {
+  c.refreshMtx.Lock()
+  stop, done := c.stop, c.done
+  c.stop, c.done = nil, nil
+  c.refreshMtx.Unlock()
+  if stop == nil {
+    return nil
+  }
+  stop()
+  select {
+  case <-done:
+    return nil
+  case <-ctx.Done():
+    return ctx.Err()
+  }
+}
@@ -0,0 +1,81 @@
+package ujwt
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Records token IDs until the token exp, Use is true on the first use only
+type ReplayStore interface {
+  Use(ctx context.Context, jti string, until time.Time) (bool, error)
+}
+
+type MemoryReplayStore struct {
+  mtx sync.Mutex
+  used map[string]time.Time
+  evictor userv.Evictor
+}
+
+func NewMemoryReplayStore() *MemoryReplayStore {
+  return &MemoryReplayStore{used: make(map[string]time.Time)}
+}
+
+func (s *MemoryReplayStore) Use(
+  ctx context.Context, jti string, until time.Time,
+) (bool, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  userv.EvictExpired(&s.evictor, s.used, func(until time.Time) time.Time {
+    return until
+  })
+  expiry, exist := s.used[jti]
+  if exist && !time.Now().After(expiry) {
+    return false, nil
+  }
+  s.used[jti] = until
+  return true, nil
+}
+
+func replayCheck(
+  ctx context.Context, store ReplayStore, claims *JWTClaims,
+) error {
+  jti, _ := claims.raw["jti"].(string)
+  if len(jti) == 0 {
+    return ErrMissingClaim{Claim: "jti"}
+  }
+  until := time.Now().Add(24 * time.Hour)
+  if claims.Exp != 0 {
+    until = time.Unix(claims.Exp, 0).Add(ClockSkew())
+  }
+  first, err := store.Use(ctx, claims.Iss + "|" + jti, until)
+  if err != nil {
+    return userv.ServiceUnavailable(err.Error())
+  }
+  if !first {
+    return ErrReplayed
+  }
+  return nil
+}
+
+// Accepts every token once for one-time operations e.g. payment capture,
+// runs after JWTAuth or JWTSession
+func ReplayGuard(store ReplayStore) userv.Middleware {
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      ctx := r.Context()
+      claims, err := claimsRequired(ctx)
+      if err == nil {
+        err = replayCheck(ctx, store, claims)
+      }
+      if err != nil {
+        userv.WriteError(w, err)
+        return
+      }
+      next(w, r)
+    }
+  }
+}
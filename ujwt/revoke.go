@@ -0,0 +1,112 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Denylist of token IDs until the token exp
+type Revoker interface {
+  Revoke(ctx context.Context, id string, until time.Time) error
+  Revoked(ctx context.Context, id string) (bool, error)
+}
+
+type MemoryRevoker struct {
+  mtx sync.Mutex
+  revoked map[string]time.Time
+  signedOut map[string]signOutEntry
+  evictor userv.Evictor
+}
+
+func NewMemoryRevoker() *MemoryRevoker {
+  return &MemoryRevoker{
+    revoked: make(map[string]time.Time),
+    signedOut: make(map[string]signOutEntry),
+  }
+}
+
+func (r *MemoryRevoker) Revoke(
+  ctx context.Context, id string, until time.Time,
+) error {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  userv.EvictExpired(&r.evictor, r.revoked, func(until time.Time) time.Time {
+    return until
+  })
+  r.revoked[id] = until
+  return nil
+}
+
+func (r *MemoryRevoker) Revoked(ctx context.Context, id string) (bool, error) {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  until, exist := r.revoked[id]
+  if !exist {
+    return false, nil
+  }
+  if time.Now().After(until) {
+    delete(r.revoked, id)
+    return false, nil
+  }
+  return true, nil
+}
+
+// The jti claim or the token hash when the token has no jti
+func RevocationID(jwt string, claims *JWTClaims) string {
+  jti, _ := claims.raw["jti"].(string)
+  if len(jti) > 0 {
+    return "jti:" + jti
+  }
+  hash := sha256.Sum256([]byte(jwt))
+  return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// Denies the token until its exp e.g. on logout
+func Revoke(ctx context.Context, revoker Revoker, jwt string) error {
+  claims, err := JWTDecodeClaims(jwt)
+  if err != nil {
+    return userv.BadRequest("invalid JWT format")
+  }
+  until := time.Now().Add(24 * time.Hour)
+  if claims.Exp != 0 {
+    until = time.Unix(claims.Exp, 0).Add(ClockSkew())
+  }
+  return revoker.Revoke(ctx, RevocationID(jwt, claims), until)
+}
+
+func revokedCheck(
+  ctx context.Context, revoker Revoker, jwt string, claims *JWTClaims,
+) error {
+  revoked, err := revoker.Revoked(ctx, RevocationID(jwt, claims))
+  if err != nil {
+    return userv.ServiceUnavailable(err.Error())
+  }
+  if revoked {
+    return ErrRevoked
+  }
+  store, ok := revoker.(SignOutStore)
+  if ok {
+    return signedOutCheck(ctx, store, claims)
+  }
+  return nil
+}
+
+// Consults the revoker after verification, wraps CachedVerify as well
+func RevocableVerify(verify VerifyFunc, revoker Revoker) VerifyFunc {
+  return func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    claims, err := verify(ctx, jwt)
+    if err != nil {
+      return nil, err
+    }
+    err = revokedCheck(ctx, revoker, jwt, claims)
+    if err != nil {
+      return nil, err
+    }
+    return claims, nil
+  }
+}
@@ -0,0 +1,95 @@
+package ujwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+const (
+  sessionClaims = "jwt:claims"
+  sessionExp = "jwt:exp"
+)
+
+func sessionExpiry(val any) int64 {
+  switch v := val.(type) {
+  case int64:
+    return v
+  case float64:
+    return int64(v)
+  case json.Number:
+    exp, _ := v.Int64()
+    return exp
+  }
+  return 0
+}
+
+// Claims of a bridged session that has not outlived the token
+func sessionJWTClaims(sess *userv.Session) (*JWTClaims, bool) {
+  exp := sessionExpiry(sess.Values[sessionExp])
+  if exp == 0 || time.Now().After(time.Unix(exp, 0)) {
+    return nil, false
+  }
+  jclaims, err := json.Marshal(sess.Values[sessionClaims])
+  if err != nil {
+    return nil, false
+  }
+  claims, err := decodeClaims(jclaims)
+  if err != nil {
+    return nil, false
+  }
+  return claims, true
+}
+
+// Exchanges a verified Bearer JWT for a server-side session bounded by the
+// token exp, so browsers send the session cookie instead of the token. Must
+// run inside mgr.Sessions()
+func JWTSession(
+  mgr *userv.SessionManager, verify VerifyFunc, extractors ...Extractor,
+) func(roles [][]string) userv.Middleware {
+  return func(roles [][]string) userv.Middleware {
+    return func(next http.HandlerFunc) http.HandlerFunc {
+      return func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        var claims *JWTClaims
+        sess, exist := userv.SessionFrom(ctx)
+        if exist {
+          claims, exist = sessionJWTClaims(sess)
+        }
+        if !exist {
+          jwt, found := extractToken(r, extractors)
+          if !found {
+            userv.WriteError(w, userv.Unautorized("missing JWT"))
+            return
+          }
+          var err error
+          claims, err = verify(ctx, jwt)
+          if err != nil {
+            userv.WriteError(w, err)
+            return
+          }
+          sess, err = mgr.Regenerate(w, r)
+          if err != nil {
+            userv.WriteError(w, userv.ServiceUnavailable(err.Error()))
+            return
+          }
+          if sess.Values == nil {
+            sess.Values = make(map[string]any)
+          }
+          sess.Values[sessionClaims] = claims.raw
+          sess.Values[sessionExp] = claims.Exp
+        }
+        err := Roles(roles)(claims)
+        if err != nil {
+          userv.WriteError(w, err)
+          return
+        }
+        ctx = WithClaims(ctx, claims)
+        ctx = userv.WithPrincipal(ctx, claims.Sub)
+        next(w, r.WithContext(ctx))
+      }
+    }
+  }
+}
@@ -0,0 +1,175 @@
+package ujwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+type signerConfig struct {
+  kid string
+  issuer string
+  audience string
+  ttl time.Duration
+}
+
+type signerOption func(cfg *signerConfig)
+
+func SignerKid(kid string) signerOption {
+  return func(cfg *signerConfig) {
+    cfg.kid = kid
+  }
+}
+
+func SignerIssuer(issuer string) signerOption {
+  return func(cfg *signerConfig) {
+    cfg.issuer = issuer
+  }
+}
+
+func SignerAudience(audience string) signerOption {
+  return func(cfg *signerConfig) {
+    cfg.audience = audience
+  }
+}
+
+func SignerTTL(ttl time.Duration) signerOption {
+  return func(cfg *signerConfig) {
+    cfg.ttl = ttl
+  }
+}
+
+type Signer struct {
+  alg string
+  key any
+  cfg *signerConfig
+}
+
+// key is *rsa.PrivateKey for RS256, *ecdsa.PrivateKey on P-256 for ES256 and
+// []byte for HS256
+func NewSigner(alg string, key any, opts ...signerOption) (*Signer, error) {
+  valid := false
+  switch alg {
+  case AlgRS256:
+    _, valid = key.(*rsa.PrivateKey)
+  case AlgES256:
+    pkey, ok := key.(*ecdsa.PrivateKey)
+    valid = ok && pkey.Curve == elliptic.P256()
+  case AlgHS256:
+    secret, ok := key.([]byte)
+    valid = ok && len(secret) >= 32
+  default:
+    return nil, fmt.Errorf("unsupported JWT signing algorithm %s", alg)
+  }
+  if !valid {
+    return nil, fmt.Errorf("invalid %s signing key", alg)
+  }
+  cfg := &signerConfig{ttl: time.Hour}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return &Signer{alg: alg, key: key, cfg: cfg}, nil
+}
+
+func (s *Signer) signature(msg []byte) ([]byte, error) {
+  hash := sha256.Sum256(msg)
+  switch key := s.key.(type) {
+  case *rsa.PrivateKey:
+    return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+  case *ecdsa.PrivateKey:
+    r, rs, err := ecdsa.Sign(rand.Reader, key, hash[:])
+    if err != nil {
+      return nil, err
+    }
+    // Fixed size r || s
+    sig := make([]byte, 64)
+    r.FillBytes(sig[:32])
+    rs.FillBytes(sig[32:])
+    return sig, nil
+  default:
+    mac := hmac.New(sha256.New, s.key.([]byte))
+    mac.Write(msg)
+    return mac.Sum(nil), nil
+  }
+}
+
+// Mints a token for the subject, custom claims override the standard ones
+func (s *Signer) Sign(subject string, custom map[string]any) (string, error) {
+  now := time.Now()
+  jti := make([]byte, 16)
+  _, _ = rand.Read(jti)
+  claims := map[string]any{
+    "sub": subject,
+    "iat": now.Unix(),
+    "exp": now.Add(s.cfg.ttl).Unix(),
+    "jti": hex.EncodeToString(jti),
+  }
+  if len(s.cfg.issuer) > 0 {
+    claims["iss"] = s.cfg.issuer
+  }
+  if len(s.cfg.audience) > 0 {
+    claims["aud"] = s.cfg.audience
+  }
+  maps.Copy(claims, custom)
+  head := jwtHeader{Alg: s.alg, Typ: "JWT", Kid: s.cfg.kid}
+  jhead, err := json.Marshal(head)
+  if err != nil {
+    return "", err
+  }
+  jclaims, err := json.Marshal(claims)
+  if err != nil {
+    return "", err
+  }
+  msg := base64.RawURLEncoding.EncodeToString(jhead) + "." +
+    base64.RawURLEncoding.EncodeToString(jclaims)
+  sig, err := s.signature([]byte(msg))
+  if err != nil {
+    return "", err
+  }
+  return msg + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// PKCS#1, PKCS#8 or SEC 1 private key
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+  block, _ := pem.Decode(data)
+  if block == nil {
+    return nil, errors.New("invalid PEM private key")
+  }
+  switch block.Type {
+  case "RSA PRIVATE KEY":
+    return x509.ParsePKCS1PrivateKey(block.Bytes)
+  case "EC PRIVATE KEY":
+    return x509.ParseECPrivateKey(block.Bytes)
+  }
+  key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+  if err != nil {
+    return nil, err
+  }
+  signer, ok := key.(crypto.Signer)
+  if !ok {
+    return nil, errors.New("unsupported PEM private key")
+  }
+  return signer, nil
+}
+
+// PKIX public key e.g. for KeyFunc of JWTVerify
+func ParsePublicKeyPEM(data []byte) (any, error) {
+  block, _ := pem.Decode(data)
+  if block == nil {
+    return nil, errors.New("invalid PEM public key")
+  }
+  return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,149 @@
+package ujwt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Optional Revoker extension rejecting tokens of a subject issued before its
+// last sign-out
+type SignOutStore interface {
+  SignOut(ctx context.Context, sub string, at, until time.Time) error
+  SignedOutAt(ctx context.Context, sub string) (time.Time, error)
+}
+
+// The latest sign-out of a subject kept until the tokens issued before it
+// expire
+type signOutEntry struct {
+  at time.Time
+  until time.Time
+}
+
+func (r *MemoryRevoker) SignOut(
+  ctx context.Context, sub string, at, until time.Time,
+) error {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  userv.EvictExpired(
+    &r.evictor, r.signedOut, func(entry signOutEntry) time.Time {
+      return entry.until
+    },
+  )
+  entry, exist := r.signedOut[sub]
+  if exist && time.Now().Before(entry.until) {
+    if entry.at.After(at) {
+      at = entry.at
+    }
+    if entry.until.After(until) {
+      until = entry.until
+    }
+  }
+  r.signedOut[sub] = signOutEntry{at: at, until: until}
+  return nil
+}
+
+func (r *MemoryRevoker) SignedOutAt(
+  ctx context.Context, sub string,
+) (time.Time, error) {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  entry, exist := r.signedOut[sub]
+  if !exist {
+    return time.Time{}, nil
+  }
+  if time.Now().After(entry.until) {
+    delete(r.signedOut, sub)
+    return time.Time{}, nil
+  }
+  return entry.at, nil
+}
+
+func signedOutCheck(
+  ctx context.Context, store SignOutStore, claims *JWTClaims,
+) error {
+  at, err := store.SignedOutAt(ctx, claims.Sub)
+  if err != nil {
+    return userv.ServiceUnavailable(err.Error())
+  }
+  if !at.IsZero() && claims.Iat <= at.Unix() {
+    return ErrRevoked
+  }
+  return nil
+}
+
+// Cognito access tokens live up to one day
+const signOutRetention = 24 * time.Hour
+
+// Records the sign-out of the subject at the current time so tokens issued
+// before it are rejected until they expire
+func SignOut(ctx context.Context, store SignOutStore, sub string) error {
+  now := time.Now()
+  return store.SignOut(ctx, sub, now, now.Add(signOutRetention + ClockSkew()))
+}
+
+// https://cognito-idp.<region>.amazonaws.com/<user pool id>
+var cognitoIssuerRe = regexp.MustCompile(
+  `^https://(cognito-idp\.[a-z]{2}(-[a-z]+)+-\d\.amazonaws\.com)/[\w-]+$`,
+)
+
+func cognitoEndpoint(issuer string) (string, error) {
+  match := cognitoIssuerRe.FindStringSubmatch(issuer)
+  if match == nil {
+    return "", fmt.Errorf("global sign-out: not a Cognito issuer %s", issuer)
+  }
+  return "https://" + match[1] + "/", nil
+}
+
+// Calls Cognito GlobalSignOut with the user access token, which invalidates
+// refresh tokens, and records the sign-out so outstanding access tokens are
+// rejected despite their exp. The token is verified first e.g. with
+// Verifier.Verify, so only the pool issuer and subject of a valid token are
+// trusted
+func GlobalSignOut(
+  ctx context.Context, httpc *ureq.Client, store SignOutStore,
+  verify VerifyFunc, accessToken string,
+) error {
+  claims, err := verify(ctx, accessToken)
+  if err != nil {
+    return err
+  }
+  if claims.TokenUse != TokenUseAccess {
+    return userv.Unautorized("invalid JWT use")
+  }
+  endpoint, err := cognitoEndpoint(claims.Iss)
+  if err != nil {
+    return err
+  }
+  _, err = httpc.POST(
+    ctx, ureq.URL(endpoint),
+    ureq.ReqJSON(map[string]string{"AccessToken": accessToken}),
+    ureq.Header("Content-Type", "application/x-amz-json-1.1"),
+    ureq.Header(
+      "X-Amz-Target", "AWSCognitoIdentityProviderService.GlobalSignOut",
+    ),
+    ureq.ReqExpect(200),
+  )
+  if err != nil {
+    return fmt.Errorf("global sign-out: %w", err)
+  }
+  return SignOut(ctx, store, claims.Sub)
+}
+
+// Same as GlobalSignOut for admins, signOut calls AdminUserGlobalSignOut
+// with AWS credentials e.g. through the AWS SDK, resolving the username of
+// the subject
+func AdminGlobalSignOut(
+  ctx context.Context, store SignOutStore, sub string,
+  signOut func(ctx context.Context, sub string) error,
+) error {
+  err := signOut(ctx, sub)
+  if err != nil {
+    return fmt.Errorf("admin global sign-out: %w", err)
+  }
+  return SignOut(ctx, store, sub)
+}
@@ -0,0 +1,84 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Local key set replacing the HTTP fetch
+type keySource func(ctx context.Context) (map[jwkID]any, error)
+
+func bytesSource(data []byte) (map[jwkID]any, error) {
+  var jwks jwkst
+  err := json.Unmarshal(data, &jwks)
+  if err != nil {
+    return nil, fmt.Errorf("JWKS parse: %w", err)
+  }
+  return jwksKeys(&jwks), nil
+}
+
+// JWKS JSON file re-read on every fetch, reload it with StartRefresh
+func JWKSFile(path string) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.source = func(ctx context.Context) (map[jwkID]any, error) {
+      data, err := os.ReadFile(path)
+      if err != nil {
+        return nil, err
+      }
+      return bytesSource(data)
+    }
+  }
+}
+
+// Embedded JWKS JSON e.g. from go:embed
+func JWKSBytes(data []byte) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.source = func(ctx context.Context) (map[jwkID]any, error) {
+      return bytesSource(data)
+    }
+  }
+}
+
+func keyAlg(pub any) (string, error) {
+  switch key := pub.(type) {
+  case *rsa.PublicKey:
+    return AlgRS256, nil
+  case *ecdsa.PublicKey:
+    switch key.Curve {
+    case elliptic.P256():
+      return AlgES256, nil
+    case elliptic.P384():
+      return AlgES384, nil
+    }
+  case ed25519.PublicKey:
+    return AlgEdDSA, nil
+  }
+  return "", fmt.Errorf("unsupported public key %T", pub)
+}
+
+// PEM public keys by kid, the algorithm is derived from the key type
+func JWKSPEM(pems map[string][]byte) jwksOption {
+  return func(cfg *jwksConfig) {
+    cfg.source = func(ctx context.Context) (map[jwkID]any, error) {
+      keys := make(map[jwkID]any, len(pems))
+      for kid, pem := range pems {
+        pub, err := ParsePublicKeyPEM(pem)
+        if err != nil {
+          return nil, fmt.Errorf("%s: %w", kid, err)
+        }
+        alg, err := keyAlg(pub)
+        if err != nil {
+          return nil, fmt.Errorf("%s: %w", kid, err)
+        }
+        keys[jwkID{kid: kid, alg: alg}] = pub
+      }
+      return keys, nil
+    }
+  }
+}
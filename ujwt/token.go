@@ -0,0 +1,153 @@
+package ujwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ureq"
+)
+
+type tokenConfig struct {
+  url string
+  scopes []string
+  refreshToken string
+  early time.Duration
+}
+
+type tokenOption func(cfg *tokenConfig)
+
+func TokenURL(url string) tokenOption {
+  return func(cfg *tokenConfig) {
+    cfg.url = url
+  }
+}
+
+func TokenScopes(scopes ...string) tokenOption {
+  return func(cfg *tokenConfig) {
+    cfg.scopes = scopes
+  }
+}
+
+// Uses the refresh_token grant instead of client_credentials
+func TokenRefresh(refreshToken string) tokenOption {
+  return func(cfg *tokenConfig) {
+    cfg.refreshToken = refreshToken
+  }
+}
+
+// Refreshes the access token the duration before its expiry
+func TokenEarly(early time.Duration) tokenOption {
+  return func(cfg *tokenConfig) {
+    cfg.early = early
+  }
+}
+
+type tokenResponse struct {
+  AccessToken string `json:"access_token"`
+  IDToken string `json:"id_token"`
+  RefreshToken string `json:"refresh_token"`
+  ExpiresIn int64 `json:"expires_in"`
+  TokenType string `json:"token_type"`
+}
+
+type tokenError struct {
+  Error string `json:"error"`
+  Description string `json:"error_description"`
+}
+
+// Cognito /oauth2/token client caching the access token
+type TokenClient struct {
+  httpc *ureq.Client
+  clientID string
+  clientSecret string
+  cfg *tokenConfig
+  mtx sync.Mutex
+  token string
+  expiry time.Time
+}
+
+// The client base URL is the Cognito domain
+func NewTokenClient(
+  httpc *ureq.Client, clientID, clientSecret string, opts ...tokenOption,
+) *TokenClient {
+  cfg := &tokenConfig{url: "/oauth2/token", early: time.Minute}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return &TokenClient{
+    httpc: httpc,
+    clientID: clientID,
+    clientSecret: clientSecret,
+    cfg: cfg,
+  }
+}
+
+func (c *TokenClient) exchange(ctx context.Context) (*tokenResponse, error) {
+  form := url.Values{"client_id": {c.clientID}}
+  if len(c.cfg.refreshToken) > 0 {
+    form.Set("grant_type", "refresh_token")
+    form.Set("refresh_token", c.cfg.refreshToken)
+  } else {
+    form.Set("grant_type", "client_credentials")
+  }
+  if len(c.cfg.scopes) > 0 {
+    form.Set("scope", strings.Join(c.cfg.scopes, " "))
+  }
+  // Public app clients have no secret
+  authz := ureq.Header("Accept", "application/json")
+  if len(c.clientSecret) > 0 {
+    authz = ureq.Basic(c.clientID, c.clientSecret)
+  }
+  var tok tokenResponse
+  var tokErr tokenError
+  res, err := c.httpc.FORM(
+    ctx, ureq.URL(c.cfg.url), ureq.FormValues(form), authz,
+    ureq.ResJSON(&tok), ureq.ErrJSON(&tokErr),
+  )
+  if err != nil {
+    return nil, err
+  }
+  if res.StatusCode != http.StatusOK {
+    return nil, fmt.Errorf(
+      "token exchange: expected %d, got %d %s", http.StatusOK, res.StatusCode,
+      strings.TrimSpace(tokErr.Error + " " + tokErr.Description),
+    )
+  }
+  if len(tok.AccessToken) == 0 {
+    return nil, errors.New("token exchange: empty access token")
+  }
+  return &tok, nil
+}
+
+// Cached access token refreshed before expiry, suitable for ureq.Bearer
+func (c *TokenClient) Token(ctx context.Context) (string, error) {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  if len(c.token) > 0 && time.Until(c.expiry) > c.cfg.early {
+    return c.token, nil
+  }
+  tok, err := c.exchange(ctx)
+  if err != nil {
+    return "", err
+  }
+  c.token = tok.AccessToken
+  c.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+  // Cognito does not rotate refresh tokens by default
+  if len(tok.RefreshToken) > 0 && len(c.cfg.refreshToken) > 0 {
+    c.cfg.refreshToken = tok.RefreshToken
+  }
+  return c.token, nil
+}
+
+// Drops the cached token e.g. after a 401 from the resource server
+func (c *TokenClient) Invalidate() {
+  c.mtx.Lock()
+  defer c.mtx.Unlock()
+  c.token = ""
+}
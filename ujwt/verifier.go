@@ -0,0 +1,124 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"sync"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Routes verification by the token iss claim to the issuer registered with
+// its own keys, algorithms and client IDs
+type Verifier struct {
+  mtx sync.RWMutex
+  issuers map[string]VerifyFunc
+  revoker Revoker
+  decryptKey *rsa.PrivateKey
+}
+
+func NewVerifier() *Verifier {
+  return &Verifier{issuers: make(map[string]VerifyFunc)}
+}
+
+func (v *Verifier) register(issuer string, verify VerifyFunc) {
+  v.mtx.Lock()
+  defer v.mtx.Unlock()
+  v.issuers[issuer] = verify
+}
+
+// Cognito user pool RS256 tokens of the token use and client IDs
+func (v *Verifier) Cognito(
+  issuer string, jwks *jwksCache, tokenUse string, clientIDs ...string,
+) {
+  v.register(issuer, func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    return JWTRS256Verify(ctx, jwt, jwks, issuer, tokenUse, clientIDs)
+  })
+}
+
+// Any issuer verified with the key function and allowed algorithms
+func (v *Verifier) Issuer(
+  issuer string, keyFunc KeyFunc, algs []string, opts ...assertOption,
+) {
+  cfg := &assertConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  cfg.issuer = issuer
+  v.register(issuer, func(ctx context.Context, jwt string) (*JWTClaims, error) {
+    claims, err := JWTVerify(ctx, jwt, keyFunc, algs...)
+    if err != nil {
+      return nil, err
+    }
+    err = assertClaims(claims, cfg)
+    if err != nil {
+      return nil, err
+    }
+    return claims, nil
+  })
+}
+
+func (v *Verifier) Provider(provider *Provider, opts ...assertOption) {
+  v.register(
+    provider.Issuer, func(ctx context.Context, jwt string) (*JWTClaims, error) {
+      return provider.Verify(ctx, jwt, opts...)
+    },
+  )
+}
+
+// Revoked tokens of any issuer fail verification
+func (v *Verifier) Revoker(revoker Revoker) {
+  v.mtx.Lock()
+  defer v.mtx.Unlock()
+  v.revoker = revoker
+}
+
+// Encrypted tokens of any issuer are decrypted before verification
+func (v *Verifier) DecryptKey(key *rsa.PrivateKey) {
+  v.mtx.Lock()
+  defer v.mtx.Unlock()
+  v.decryptKey = key
+}
+
+func (v *Verifier) Verify(ctx context.Context, jwt string) (*JWTClaims, error) {
+  v.mtx.RLock()
+  decryptKey := v.decryptKey
+  v.mtx.RUnlock()
+  if decryptKey != nil && IsJWE(jwt) {
+    payload, err := JWEDecrypt(jwt, decryptKey)
+    if err != nil {
+      return nil, err
+    }
+    jwt = string(payload)
+  }
+  // The unverified issuer only selects the verification
+  unverified, err := JWTDecodeClaims(jwt)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT format")
+  }
+  v.mtx.RLock()
+  verify, exist := v.issuers[unverified.Iss]
+  revoker := v.revoker
+  v.mtx.RUnlock()
+  if !exist {
+    return nil, ErrUnknownIssuer
+  }
+  if revoker != nil {
+    verify = RevocableVerify(verify, revoker)
+  }
+  return verify(ctx, jwt)
+}
+
+func (v *Verifier) AssertPolicy(
+  ctx context.Context, jwt string, policy Policy,
+) (*JWTClaims, error) {
+  claims, err := v.Verify(ctx, jwt)
+  if err != nil {
+    return nil, err
+  }
+  err = policy(claims)
+  if err != nil {
+    return nil, err
+  }
+  return claims, nil
+}
@@ -0,0 +1,175 @@
+package ujwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+const (
+  AlgRS256 = "RS256"
+  AlgHS256 = "HS256"
+  AlgES256 = "ES256"
+  AlgES384 = "ES384"
+  AlgEdDSA = "EdDSA"
+)
+
+// Returns []byte for HS256, *rsa.PublicKey for RS256, *ecdsa.PublicKey for
+// ES256/ES384 and ed25519.PublicKey for EdDSA
+type KeyFunc func(ctx context.Context, alg, kid string) (any, error)
+
+type jwtParts struct {
+  head jwtHeader
+  msg []byte
+  sig []byte
+  claims []byte
+}
+
+func parseJWT(jwt string) (*jwtParts, error) {
+  parts := strings.Split(jwt, ".")
+  if len(parts) != 3 {
+    return nil, userv.Unautorized("invalid JWT format")
+  }
+  ehead, eclaims, esig := parts[0], parts[1], parts[2]
+  jhead, err := base64.RawURLEncoding.DecodeString(ehead)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT header encoding")
+  }
+  var p jwtParts
+  err = json.Unmarshal(jhead, &p.head)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT header format")
+  }
+  p.sig, err = base64.RawURLEncoding.DecodeString(esig)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT signature format")
+  }
+  p.claims, err = base64.RawURLEncoding.DecodeString(eclaims)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT claims encoding")
+  }
+  p.msg = []byte(ehead + "." + eclaims)
+  return &p, nil
+}
+
+func verifyECDSA(
+  pub *ecdsa.PublicKey, curve elliptic.Curve, hash, sig []byte,
+) bool {
+  size := (curve.Params().BitSize + 7) / 8
+  if pub.Curve != curve || len(sig) != 2 * size {
+    return false
+  }
+  r := new(big.Int).SetBytes(sig[:size])
+  s := new(big.Int).SetBytes(sig[size:])
+  return ecdsa.Verify(pub, hash, r, s)
+}
+
+// The key type must match the algorithm to prevent algorithm confusion
+func verifySignature(alg string, key any, msg, sig []byte) error {
+  valid := false
+  switch alg {
+  case AlgRS256:
+    pub, ok := key.(*rsa.PublicKey)
+    if ok {
+      hash := sha256.Sum256(msg)
+      valid = rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig) == nil
+    }
+  case AlgHS256:
+    secret, ok := key.([]byte)
+    if ok && len(secret) > 0 {
+      mac := hmac.New(sha256.New, secret)
+      mac.Write(msg)
+      valid = hmac.Equal(mac.Sum(nil), sig)
+    }
+  case AlgES256:
+    pub, ok := key.(*ecdsa.PublicKey)
+    if ok {
+      hash := sha256.Sum256(msg)
+      valid = verifyECDSA(pub, elliptic.P256(), hash[:], sig)
+    }
+  case AlgES384:
+    pub, ok := key.(*ecdsa.PublicKey)
+    if ok {
+      hash := sha512.Sum384(msg)
+      valid = verifyECDSA(pub, elliptic.P384(), hash[:], sig)
+    }
+  case AlgEdDSA:
+    pub, ok := key.(ed25519.PublicKey)
+    if ok {
+      valid = ed25519.Verify(pub, msg, sig)
+    }
+  default:
+    return userv.Unautorized("unsupported JWT signature algorithm")
+  }
+  if !valid {
+    return ErrInvalidSignature
+  }
+  return nil
+}
+
+// Verifies the signature with the algorithm from the token header, which must
+// be one of algs, and exp, nbf and iat. Issuer and audience are checked by
+// the caller
+func JWTVerify(
+  ctx context.Context, jwt string, keyFunc KeyFunc, algs ...string,
+) (claims *JWTClaims, err error) {
+  defer func(start time.Time) {
+    observeVerify(start, err)
+  }(time.Now())
+  return jwtVerify(ctx, jwt, keyFunc, algs...)
+}
+
+func jwtVerify(
+  ctx context.Context, jwt string, keyFunc KeyFunc, algs ...string,
+) (*JWTClaims, error) {
+  p, err := parseJWT(jwt)
+  if err != nil {
+    return nil, err
+  }
+  if !slices.Contains(algs, p.head.Alg) {
+    return nil, ErrAlgNotAllowed
+  }
+  key, err := keyFunc(ctx, p.head.Alg, p.head.Kid)
+  if err != nil {
+    var unauthorized userv.Unautorized
+    if errors.As(err, &unauthorized) {
+      return nil, err
+    }
+    return nil, userv.Unautorized(err.Error())
+  }
+  err = verifySignature(p.head.Alg, key, p.msg, p.sig)
+  if err != nil {
+    return nil, err
+  }
+  claims, err := decodeClaims(p.claims)
+  if err != nil {
+    return nil, userv.Unautorized("invalid JWT claims format")
+  }
+  err = timeClaimsCheck(claims)
+  if err != nil {
+    return nil, err
+  }
+  return claims, nil
+}
+
+// HS256 shared secret key function
+func SecretKey(secret []byte) KeyFunc {
+  return func(ctx context.Context, alg, kid string) (any, error) {
+    return secret, nil
+  }
+}
@@ -0,0 +1,129 @@
+package uquery
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Counts the statement and its DB time against the userv.QueryBudget of the
+// context, if any
+func Track(ctx context.Context, query func() error) error {
+  return userv.TrackQuery(ctx, query)
+}
+
+func trackedExec(
+  ctx context.Context, db Executor, sql string, args ...any,
+) (pgconn.CommandTag, error) {
+  var tag pgconn.CommandTag
+  err := Track(ctx, func() error {
+    var err error
+    tag, err = db.Exec(ctx, sql, args...)
+    return err
+  })
+  return tag, err
+}
+
+// Transaction counting every statement against the context budget, used by
+// RunScript and IdempotentWrite
+type trackedTx struct {
+  pgx.Tx
+}
+
+func (t *trackedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+  tx, err := t.Tx.Begin(ctx)
+  if err != nil {
+    return nil, err
+  }
+  return &trackedTx{Tx: tx}, nil
+}
+
+func (t *trackedTx) Exec(
+  ctx context.Context, sql string, args ...any,
+) (pgconn.CommandTag, error) {
+  return trackedExec(ctx, t.Tx, sql, args...)
+}
+
+func (t *trackedTx) Query(
+  ctx context.Context, sql string, args ...any,
+) (pgx.Rows, error) {
+  var rows pgx.Rows
+  err := Track(ctx, func() error {
+    var err error
+    rows, err = t.Tx.Query(ctx, sql, args...)
+    return err
+  })
+  return rows, err
+}
+
+type trackedRow struct {
+  ctx context.Context
+  row pgx.Row
+}
+
+// The row is read on Scan
+func (r *trackedRow) Scan(dest ...any) error {
+  return Track(r.ctx, func() error {
+    return r.row.Scan(dest...)
+  })
+}
+
+func (t *trackedTx) QueryRow(
+  ctx context.Context, sql string, args ...any,
+) pgx.Row {
+  return &trackedRow{ctx: ctx, row: t.Tx.QueryRow(ctx, sql, args...)}
+}
+
+func (t *trackedTx) CopyFrom(
+  ctx context.Context, table pgx.Identifier, columns []string,
+  src pgx.CopyFromSource,
+) (int64, error) {
+  var n int64
+  err := Track(ctx, func() error {
+    var err error
+    n, err = t.Tx.CopyFrom(ctx, table, columns, src)
+    return err
+  })
+  return n, err
+}
+
+type errBatchResults struct {
+  err error
+}
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) {
+  return pgconn.CommandTag{}, r.err
+}
+
+func (r errBatchResults) Query() (pgx.Rows, error) {
+  return nil, r.err
+}
+
+func (r errBatchResults) QueryRow() pgx.Row {
+  return r
+}
+
+func (r errBatchResults) Scan(dest ...any) error {
+  return r.err
+}
+
+func (r errBatchResults) Close() error {
+  return r.err
+}
+
+// The batch counts as one statement
+func (t *trackedTx) SendBatch(
+  ctx context.Context, batch *pgx.Batch,
+) pgx.BatchResults {
+  var res pgx.BatchResults
+  err := Track(ctx, func() error {
+    res = t.Tx.SendBatch(ctx, batch)
+    return nil
+  })
+  if res == nil {
+    return errBatchResults{err: err}
+  }
+  return res
+}
@@ -0,0 +1,127 @@
+package uquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/volodymyrprokopyuk/go-util/ucache"
+)
+
+// pgxpool.Pool, pgx.Conn and pgx.Tx
+type Executor interface {
+  Exec(
+    ctx context.Context, sql string, args ...any,
+  ) (pgconn.CommandTag, error)
+}
+
+// Opt-in result set cache for reference tables invalidated by table
+type QueryCache struct {
+  cache *ucache.Cache[string, any]
+  ttl time.Duration
+}
+
+func NewQueryCache(size int, ttl time.Duration) *QueryCache {
+  return &QueryCache{cache: ucache.New[string, any](size), ttl: ttl}
+}
+
+// |table1|table2|fingerprint of the normalized statement and parameters
+func queryCacheKey(tables []string, sql string, args []any) string {
+  h := sha256.New()
+  sql = strings.Join(strings.Fields(sql), " ")
+  _, _ = fmt.Fprintf(h, "%s\n%#v", sql, args)
+  prefix := "|" + strings.Join(tables, "|") + "|"
+  return prefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Returns the cached result of the statement reading the tables or runs the
+// query and caches its result
+func CachedQuery[T any](
+  ctx context.Context, qc *QueryCache, tables []string, sql string,
+  args []any, query func(ctx context.Context) (T, error),
+) (T, error) {
+  key := queryCacheKey(tables, sql, args)
+  cached, exist := qc.cache.Get(key)
+  if exist {
+    val, valid := cached.(T)
+    if valid {
+      return val, nil
+    }
+  }
+  var val T
+  err := Track(ctx, func() error {
+    var err error
+    val, err = query(ctx)
+    return err
+  })
+  if err != nil {
+    return val, err
+  }
+  qc.cache.Set(key, val, qc.ttl)
+  return val, nil
+}
+
+// Drops the cached results reading any of the tables
+func (qc *QueryCache) Invalidate(tables ...string) int {
+  return qc.cache.DeleteFunc(func(key string) bool {
+    for _, table := range tables {
+      if strings.Contains(key, "|" + table + "|") {
+        return true
+      }
+    }
+    return false
+  })
+}
+
+// Executes the write and invalidates the tables on success. Within a
+// transaction invalidate again after commit to drop results cached meanwhile
+func (qc *QueryCache) Exec(
+  ctx context.Context, db Executor, tables []string, sql string, args ...any,
+) (pgconn.CommandTag, error) {
+  tag, err := trackedExec(ctx, db, sql, args...)
+  if err != nil {
+    return tag, err
+  }
+  qc.Invalidate(tables...)
+  return tag, nil
+}
+
+// Invalidates the table in all instances listening on the channel
+func NotifyInvalidate(
+  ctx context.Context, db Executor, channel, table string,
+) error {
+  _, err := trackedExec(ctx, db, "SELECT pg_notify($1, $2)", channel, table)
+  return err
+}
+
+// Invalidates tables named by notifications on the channel until the context
+// is canceled e.g. from a trigger calling pg_notify(channel, TG_TABLE_NAME)
+func (qc *QueryCache) Listen(
+  ctx context.Context, pool *pgxpool.Pool, channel string,
+) error {
+  conn, err := pool.Acquire(ctx)
+  if err != nil {
+    return err
+  }
+  defer conn.Release()
+  _, err = conn.Exec(ctx, "LISTEN " + pgx.Identifier{channel}.Sanitize())
+  if err != nil {
+    return err
+  }
+  for {
+    notice, err := conn.Conn().WaitForNotification(ctx)
+    if err != nil {
+      if ctx.Err() != nil {
+        return nil
+      }
+      return err
+    }
+    qc.Invalidate(notice.Payload)
+  }
+}
@@ -0,0 +1,102 @@
+package uquery
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Scan target and query argument for a Go string enum mapped to a Postgres
+// enum: row.Scan(uquery.Enum(&status, statuses...))
+type EnumColumn[T ~string] struct {
+  val *T
+  allowed []T
+}
+
+func Enum[T ~string](val *T, allowed ...T) *EnumColumn[T] {
+  return &EnumColumn[T]{val: val, allowed: allowed}
+}
+
+func (e *EnumColumn[T]) check(val T) error {
+  if len(e.allowed) > 0 && !slices.Contains(e.allowed, val) {
+    return fmt.Errorf("invalid enum value %q: expected one of %v", val, e.allowed)
+  }
+  return nil
+}
+
+func (e *EnumColumn[T]) Scan(src any) error {
+  var val T
+  switch s := src.(type) {
+  case string:
+    val = T(s)
+  case []byte:
+    val = T(s)
+  case nil:
+    return errors.New("invalid enum value: NULL")
+  default:
+    return fmt.Errorf("invalid enum type %T", src)
+  }
+  err := e.check(val)
+  if err != nil {
+    return err
+  }
+  *e.val = val
+  return nil
+}
+
+func (e *EnumColumn[T]) Value() (driver.Value, error) {
+  err := e.check(*e.val)
+  if err != nil {
+    return nil, err
+  }
+  return string(*e.val), nil
+}
+
+// Both SQL NULL and JSON null scan to Valid = false
+type JSONB[T any] struct {
+  V T
+  Valid bool
+}
+
+func NewJSONB[T any](val T) JSONB[T] {
+  return JSONB[T]{V: val, Valid: true}
+}
+
+func (j *JSONB[T]) Scan(src any) error {
+  var zero T
+  j.V, j.Valid = zero, false
+  var buf []byte
+  switch s := src.(type) {
+  case nil:
+    return nil
+  case string:
+    buf = []byte(s)
+  case []byte:
+    buf = s
+  default:
+    return fmt.Errorf("invalid JSONB type %T", src)
+  }
+  if bytes.Equal(bytes.TrimSpace(buf), []byte("null")) {
+    return nil
+  }
+  err := json.Unmarshal(buf, &j.V)
+  if err != nil {
+    return err
+  }
+  j.Valid = true
+  return nil
+}
+
+func (j JSONB[T]) Value() (driver.Value, error) {
+  if !j.Valid {
+    return nil, nil
+  }
+  jval, err := json.Marshal(j.V)
+  if err != nil {
+    return nil, err
+  }
+  return string(jval), nil
+}
@@ -0,0 +1,128 @@
+package uquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/volodymyrprokopyuk/go-util/urand"
+)
+
+// Serialization failure and deadlock
+var retrySQLStates = []string{"40001", "40P01"}
+
+var errAmbiguousCommit = errors.New("ambiguous commit")
+
+// Serialization failures, deadlocks, errors before sending, network errors
+// and an ambiguous commit are retried, other errors e.g. of the write itself
+// are returned immediately
+func writeRetryable(err error) bool {
+  if errors.Is(err, context.Canceled) ||
+    errors.Is(err, context.DeadlineExceeded) {
+    return false
+  }
+  var pgErr *pgconn.PgError
+  if errors.As(err, &pgErr) {
+    return slices.Contains(retrySQLStates, pgErr.Code)
+  }
+  var netErr net.Error
+  return errors.Is(err, errAmbiguousCommit) || pgconn.SafeToRetry(err) ||
+    errors.As(err, &netErr)
+}
+
+func IdempotencyToken() string {
+  return urand.RandHex(32)
+}
+
+func idempotentWrite(
+  ctx context.Context, pool *pgxpool.Pool, table, token string,
+  write func(ctx context.Context, tx pgx.Tx) error,
+) (bool, error) {
+  ptx, err := pool.Begin(ctx)
+  if err != nil {
+    return false, err
+  }
+  defer func() {
+    _ = ptx.Rollback(context.WithoutCancel(ctx))
+  }()
+  tx := &trackedTx{Tx: ptx}
+  query := fmt.Sprintf(
+    "insert into %s (token) values ($1) on conflict (token) do nothing",
+    pgx.Identifier{table}.Sanitize(),
+  )
+  tag, err := tx.Exec(ctx, query, token)
+  if err != nil {
+    return false, err
+  }
+  if tag.RowsAffected() == 0 {
+    // Applied by a previous attempt
+    return false, nil
+  }
+  err = write(ctx, tx)
+  if err != nil {
+    return false, err
+  }
+  err = ptx.Commit(ctx)
+  if err != nil {
+    var pgErr *pgconn.PgError
+    if !errors.As(err, &pgErr) {
+      // The commit may have been applied, the token tells on retry
+      return false, fmt.Errorf("%w: %w", errAmbiguousCommit, err)
+    }
+    return false, err
+  }
+  return true, nil
+}
+
+// Applies the write at most once per token: the token is stored in the dedup
+// table in the same transaction, so a retry after an ambiguous failure finds
+// it and reports applied false. The token is generated when empty
+//
+//   create table idempotency (
+//     token text primary key,
+//     created_at timestamptz not null default now()
+//   );
+func IdempotentWrite(
+  ctx context.Context, pool *pgxpool.Pool, table, token string, retries int,
+  write func(ctx context.Context, tx pgx.Tx) error,
+) (bool, error) {
+  if len(token) == 0 {
+    token = IdempotencyToken()
+  }
+  for attempt := 0; ; attempt++ {
+    applied, err := idempotentWrite(ctx, pool, table, token, write)
+    if err == nil || attempt >= retries || !writeRetryable(err) {
+      return applied, err
+    }
+    delay := time.Duration(urand.RandInt(50, 100 << min(attempt, 6))) *
+      time.Millisecond
+    timer := time.NewTimer(delay)
+    select {
+    case <-timer.C:
+    case <-ctx.Done():
+      timer.Stop()
+      return false, errors.Join(err, ctx.Err())
+    }
+  }
+}
+
+// Deletes tokens older than the retention
+func PruneIdempotency(
+  ctx context.Context, pool *pgxpool.Pool, table string,
+  retention time.Duration,
+) (int64, error) {
+  query := fmt.Sprintf(
+    "delete from %s where created_at < $1", pgx.Identifier{table}.Sanitize(),
+  )
+  tag, err := trackedExec(ctx, pool, query, time.Now().Add(-retention))
+  if err != nil {
+    return 0, err
+  }
+  return tag.RowsAffected(), nil
+}
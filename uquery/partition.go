@@ -0,0 +1,219 @@
+package uquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// pgxpool.Pool, pgxpool.Conn, pgx.Conn and pgx.Tx
+type QueryExecutor interface {
+  Executor
+  Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type PartitionPeriod int
+
+const (
+  PartitionDaily PartitionPeriod = iota
+  PartitionMonthly
+)
+
+func (p PartitionPeriod) start(t time.Time) time.Time {
+  y, m, d := t.UTC().Date()
+  if p == PartitionMonthly {
+    return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+  }
+  return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (p PartitionPeriod) add(t time.Time, n int) time.Time {
+  if p == PartitionMonthly {
+    return t.AddDate(0, n, 0)
+  }
+  return t.AddDate(0, 0, n)
+}
+
+func (p PartitionPeriod) layout() string {
+  if p == PartitionMonthly {
+    return "200601"
+  }
+  return "20060102"
+}
+
+// Range partitions of a table partitioned by a timestamptz column named
+// <table>_p20250131 or <table>_p202501 in the schema of the table, the table
+// is optionally schema-qualified e.g. public.audit
+//
+//   create table audit (
+//     ...
+//     created_at timestamptz not null
+//   ) partition by range (created_at);
+type PartitionPolicy struct {
+  Table string
+  Period PartitionPeriod
+  Ahead int // partitions created ahead of the current one
+  Retention int // past partitions kept, 0 keeps all
+  Drop bool // drops detached partitions
+}
+
+func (p *PartitionPolicy) name(start time.Time) string {
+  return p.Table + "_p" + start.Format(p.Period.layout())
+}
+
+// Schema and table or the table
+func (p *PartitionPolicy) table() pgx.Identifier {
+  return pgx.Identifier(strings.Split(p.Table, "."))
+}
+
+// Partition in the schema of the table
+func (p *PartitionPolicy) partition(name string) pgx.Identifier {
+  id := p.table()
+  id[len(id) - 1] = name
+  return id
+}
+
+// Creates the current and ahead partitions if they do not exist
+func (p *PartitionPolicy) Create(
+  ctx context.Context, db Executor, now time.Time,
+) error {
+  start := p.Period.start(now)
+  for i := range p.Ahead + 1 {
+    from := p.Period.add(start, i)
+    to := p.Period.add(from, 1)
+    query := fmt.Sprintf(
+      "create table if not exists %s partition of %s " +
+        "for values from ('%s') to ('%s')",
+      pgx.Identifier(strings.Split(p.name(from), ".")).Sanitize(),
+      p.table().Sanitize(),
+      from.Format(time.RFC3339), to.Format(time.RFC3339),
+    )
+    _, err := trackedExec(ctx, db, query)
+    if err != nil {
+      return fmt.Errorf("create partition %s: %w", p.name(from), err)
+    }
+  }
+  return nil
+}
+
+// Detaches and optionally drops partitions older than the retention,
+// partitions not following the naming are left alone
+func (p *PartitionPolicy) Expire(
+  ctx context.Context, db QueryExecutor, now time.Time,
+) ([]string, error) {
+  if p.Retention <= 0 {
+    return nil, nil
+  }
+  var rows pgx.Rows
+  err := Track(ctx, func() error {
+    var err error
+    rows, err = db.Query(ctx, `select c.relname from pg_inherits i
+join pg_class c on c.oid = i.inhrelid
+where i.inhparent = $1::regclass`, p.table().Sanitize())
+    return err
+  })
+  if err != nil {
+    return nil, err
+  }
+  names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+  if err != nil {
+    return nil, err
+  }
+  oldest := p.Period.add(p.Period.start(now), -p.Retention)
+  table := p.table()
+  prefix := table[len(table) - 1] + "_p"
+  var expired []string
+  for _, name := range names {
+    suffix, found := strings.CutPrefix(name, prefix)
+    if !found {
+      continue
+    }
+    start, err := time.Parse(p.Period.layout(), suffix)
+    if err != nil || !start.Before(oldest) {
+      continue
+    }
+    partition := p.partition(name).Sanitize()
+    query := fmt.Sprintf(
+      "alter table %s detach partition %s", table.Sanitize(), partition,
+    )
+    _, err = trackedExec(ctx, db, query)
+    if err != nil {
+      return expired, fmt.Errorf("detach partition %s: %w", name, err)
+    }
+    if p.Drop {
+      _, err = trackedExec(ctx, db, "drop table " + partition)
+      if err != nil {
+        return expired, fmt.Errorf("drop partition %s: %w", name, err)
+      }
+    }
+    expired = append(expired, name)
+  }
+  return expired, nil
+}
+
+// Creates and expires partitions under a session advisory lock so only one
+// instance maintains the table at a time, returns false when the lock is
+// held elsewhere
+func (p *PartitionPolicy) Maintain(
+  ctx context.Context, pool *pgxpool.Pool,
+) (bool, error) {
+  conn, err := pool.Acquire(ctx)
+  if err != nil {
+    return false, err
+  }
+  defer conn.Release()
+  var locked bool
+  err = conn.QueryRow(
+    ctx, "select pg_try_advisory_lock(hashtext($1))", "partition:" + p.Table,
+  ).Scan(&locked)
+  if err != nil || !locked {
+    return false, err
+  }
+  defer func() {
+    _, _ = conn.Exec(
+      context.WithoutCancel(ctx), "select pg_advisory_unlock(hashtext($1))",
+      "partition:" + p.Table,
+    )
+  }()
+  now := time.Now()
+  err = p.Create(ctx, conn, now)
+  if err != nil {
+    return true, err
+  }
+  _, err = p.Expire(ctx, conn, now)
+  return true, err
+}
+
+// Maintenance job for userv.Background, runs at start and every interval
+// until the context is canceled
+func PartitionMaintenance(
+  pool *pgxpool.Pool, interval time.Duration, policies ...*PartitionPolicy,
+) func(ctx context.Context) error {
+  return func(ctx context.Context) error {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+      for _, policy := range policies {
+        start := time.Now()
+        locked, err := policy.Maintain(ctx, pool)
+        if ctx.Err() != nil {
+          return nil
+        }
+        userv.LogAction(
+          "partition maintenance", err, start, policy.Table,
+          fmt.Sprintf("locked %v", locked),
+        )
+      }
+      select {
+      case <-ctx.Done():
+        return nil
+      case <-ticker.C:
+      }
+    }
+  }
+}
@@ -0,0 +1,193 @@
+package uquery
+
+import (
+	"context"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Only options set explicitly override the DSN
+type poolConfig struct {
+  maxConns int32
+  minConns int32
+  maxConnsSet bool
+  healthCheck time.Duration
+  healthCheckSet bool
+  maxConnLifetime time.Duration
+  maxConnLifetimeSet bool
+  statementTimeout time.Duration
+  statementTimeoutSet bool
+  appName string
+  afterConnect []func(ctx context.Context, conn *pgx.Conn) error
+  lifecycle *userv.Lifecycle
+}
+
+type poolOption func(cfg *poolConfig)
+
+func PoolMaxConns(maxConns, minConns int) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.maxConns = int32(maxConns)
+    cfg.minConns = int32(minConns)
+    cfg.maxConnsSet = true
+  }
+}
+
+func PoolHealthCheck(period time.Duration) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.healthCheck = period
+    cfg.healthCheckSet = true
+  }
+}
+
+func PoolMaxConnLifetime(lifetime time.Duration) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.maxConnLifetime = lifetime
+    cfg.maxConnLifetimeSet = true
+  }
+}
+
+func PoolStatementTimeout(timeout time.Duration) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.statementTimeout = timeout
+    cfg.statementTimeoutSet = true
+  }
+}
+
+func PoolAppName(name string) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.appName = name
+  }
+}
+
+// Runs on every new connection e.g. to register custom types
+func PoolAfterConnect(
+  hook func(ctx context.Context, conn *pgx.Conn) error,
+) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.afterConnect = append(cfg.afterConnect, hook)
+  }
+}
+
+// Closes the pool on lifecycle shutdown
+func PoolLifecycle(lifecycle *userv.Lifecycle) poolOption {
+  return func(cfg *poolConfig) {
+    cfg.lifecycle = lifecycle
+  }
+}
+
+// Whether the URL or keyword/value DSN sets the key
+func dsnHas(dsn, key string) bool {
+  if strings.HasPrefix(dsn, "postgres://") ||
+    strings.HasPrefix(dsn, "postgresql://") {
+    u, err := url.Parse(dsn)
+    return err == nil && u.Query().Has(key)
+  }
+  for field := range strings.FieldsSeq(dsn) {
+    name, _, _ := strings.Cut(field, "=")
+    if strings.TrimSpace(name) == key {
+      return true
+    }
+  }
+  return false
+}
+
+// Connects and pings the pool, options override the DSN, defaults apply to
+// settings the DSN does not have
+func NewPool(
+  ctx context.Context, dsn string, opts ...poolOption,
+) (*pgxpool.Pool, error) {
+  cfg := &poolConfig{
+    maxConns: int32(max(4, runtime.NumCPU() * 2)),
+    healthCheck: 30 * time.Second,
+    maxConnLifetime: time.Hour,
+    statementTimeout: 30 * time.Second,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  pcfg, err := pgxpool.ParseConfig(dsn)
+  if err != nil {
+    return nil, err
+  }
+  if cfg.maxConnsSet || !dsnHas(dsn, "pool_max_conns") {
+    pcfg.MaxConns = cfg.maxConns
+  }
+  if cfg.maxConnsSet || !dsnHas(dsn, "pool_min_conns") {
+    pcfg.MinConns = cfg.minConns
+  }
+  if cfg.healthCheckSet || !dsnHas(dsn, "pool_health_check_period") {
+    pcfg.HealthCheckPeriod = cfg.healthCheck
+  }
+  if cfg.maxConnLifetimeSet || !dsnHas(dsn, "pool_max_conn_lifetime") {
+    pcfg.MaxConnLifetime = cfg.maxConnLifetime
+  }
+  params := pcfg.ConnConfig.RuntimeParams
+  // statement_timeout directly or in options=-c statement_timeout=...
+  _, dsnTimeout := params["statement_timeout"]
+  dsnTimeout = dsnTimeout ||
+    strings.Contains(params["options"], "statement_timeout")
+  if cfg.statementTimeoutSet || !dsnTimeout {
+    params["statement_timeout"] = strconv.FormatInt(
+      cfg.statementTimeout.Milliseconds(), 10,
+    )
+  }
+  if len(cfg.appName) > 0 {
+    params["application_name"] = cfg.appName
+  }
+  if len(cfg.afterConnect) > 0 {
+    pcfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+      for _, hook := range cfg.afterConnect {
+        err := hook(ctx, conn)
+        if err != nil {
+          return err
+        }
+      }
+      return nil
+    }
+  }
+  pool, err := pgxpool.NewWithConfig(ctx, pcfg)
+  if err != nil {
+    return nil, err
+  }
+  err = pool.Ping(ctx)
+  if err != nil {
+    pool.Close()
+    return nil, err
+  }
+  if cfg.lifecycle != nil {
+    cfg.lifecycle.OnShutdown("postgres pool", func(ctx context.Context) error {
+      pool.Close()
+      return nil
+    })
+  }
+  return pool, nil
+}
+
+// Readiness check for health endpoints
+func PoolCheck(pool *pgxpool.Pool) func(ctx context.Context) error {
+  return func(ctx context.Context) error {
+    return pool.Ping(ctx)
+  }
+}
+
+// Pool gauges for metrics and logs
+func PoolStats(pool *pgxpool.Pool) map[string]int64 {
+  stat := pool.Stat()
+  return map[string]int64{
+    "acquired": int64(stat.AcquiredConns()),
+    "idle": int64(stat.IdleConns()),
+    "total": int64(stat.TotalConns()),
+    "max": int64(stat.MaxConns()),
+    "acquireCount": stat.AcquireCount(),
+    "acquireWaitMs": stat.AcquireDuration().Milliseconds(),
+    "emptyAcquireCount": stat.EmptyAcquireCount(),
+    "canceledAcquireCount": stat.CanceledAcquireCount(),
+  }
+}
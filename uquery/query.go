@@ -1,6 +1,8 @@
 package uquery
 
 import (
+	"context"
+	"errors"
 	"regexp"
 	"strings"
 	"time"
@@ -25,10 +27,18 @@ func HTTPError(err error) error {
 }
 
 func Retry(query func() error, times int, failure string) error {
+  return RetryContext(context.Background(), query, times, failure)
+}
+
+// Same as Retry with every attempt counted against the context budget
+func RetryContext(
+  ctx context.Context, query func() error, times int, failure string,
+) error {
   var err error
   for range times {
-    err = query()
-    if err != nil &&
+    err = Track(ctx, query)
+    var budgetErr *userv.QueryBudgetExceeded
+    if err != nil && !errors.As(err, &budgetErr) &&
       strings.Contains(err.Error(), failure) {
       time.Sleep(time.Duration(urand.RandInt(500, 800)) * time.Millisecond)
       continue
@@ -0,0 +1,85 @@
+package uquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// userv.QuotaStore backed by a Postgres table
+//
+//   create table quota (
+//     key text primary key,
+//     count bigint not null,
+//     expiry timestamptz not null
+//   );
+type PostgresQuotaStore struct {
+  db QueryExecutor
+  table string
+}
+
+// The pool or a connection
+func NewPostgresQuotaStore(
+  db QueryExecutor, table string,
+) *PostgresQuotaStore {
+  return &PostgresQuotaStore{db: db, table: pgx.Identifier{table}.Sanitize()}
+}
+
+func (s *PostgresQuotaStore) count(
+  ctx context.Context, query string, args ...any,
+) (int64, error) {
+  var count int64
+  err := Track(ctx, func() error {
+    rows, err := s.db.Query(ctx, query, args...)
+    if err != nil {
+      return err
+    }
+    count, err = pgx.CollectOneRow(rows, pgx.RowTo[int64])
+    return err
+  })
+  return count, err
+}
+
+func (s *PostgresQuotaStore) Incr(
+  ctx context.Context, key string, expiry time.Time,
+) (int64, error) {
+  query := fmt.Sprintf(`insert into %s as q (key, count, expiry)
+values ($1, 1, $2)
+on conflict (key) do update
+set count = case when q.expiry < now() then 1 else q.count + 1 end,
+  expiry = excluded.expiry
+returning count`, s.table)
+  return s.count(ctx, query, key, expiry)
+}
+
+func (s *PostgresQuotaStore) Get(
+  ctx context.Context, key string,
+) (int64, error) {
+  query := fmt.Sprintf(
+    `select count from %s where key = $1 and expiry >= now()`, s.table,
+  )
+  count, err := s.count(ctx, query, key)
+  if errors.Is(err, pgx.ErrNoRows) {
+    return 0, nil
+  }
+  return count, err
+}
+
+func (s *PostgresQuotaStore) Reset(ctx context.Context, key string) error {
+  query := fmt.Sprintf(`delete from %s where key = $1`, s.table)
+  _, err := trackedExec(ctx, s.db, query, key)
+  return err
+}
+
+// Removes expired counters, run periodically e.g. with userv.Background
+func (s *PostgresQuotaStore) Prune(ctx context.Context) (int64, error) {
+  query := fmt.Sprintf(`delete from %s where expiry < now()`, s.table)
+  tag, err := trackedExec(ctx, s.db, query)
+  if err != nil {
+    return 0, err
+  }
+  return tag.RowsAffected(), nil
+}
@@ -0,0 +1,244 @@
+package uquery
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Column struct {
+  Type string `json:"type"`
+  Nullable bool `json:"nullable"`
+  Default string `json:"default,omitempty"`
+}
+
+type Table struct {
+  Columns map[string]Column `json:"columns"`
+  // Index name to pg_indexes.indexdef
+  Indexes map[string]string `json:"indexes"`
+}
+
+// Schema snapshot, store ReadSchema of a freshly migrated database as JSON
+type Schema struct {
+  Name string `json:"name"`
+  Tables map[string]Table `json:"tables"`
+}
+
+// Reads tables, columns and indexes of the schema, partitions are left out
+func ReadSchema(
+  ctx context.Context, db QueryExecutor, name string,
+) (*Schema, error) {
+  schema := &Schema{Name: name, Tables: make(map[string]Table)}
+  rows, err := db.Query(ctx, `select c.relname, a.attname,
+  format_type(a.atttypid, a.atttypmod), not a.attnotnull,
+  coalesce(pg_get_expr(d.adbin, d.adrelid), '')
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+join pg_namespace n on n.oid = c.relnamespace
+left join pg_attrdef d on d.adrelid = a.attrelid and d.adnum = a.attnum
+where n.nspname = $1 and c.relkind in ('r', 'p') and not c.relispartition
+  and a.attnum > 0 and not a.attisdropped`, name)
+  if err != nil {
+    return nil, err
+  }
+  var table, column string
+  var col Column
+  _, err = pgx.ForEachRow(
+    rows, []any{&table, &column, &col.Type, &col.Nullable, &col.Default},
+    func() error {
+      tbl, exist := schema.Tables[table]
+      if !exist {
+        tbl = Table{
+          Columns: make(map[string]Column), Indexes: make(map[string]string),
+        }
+        schema.Tables[table] = tbl
+      }
+      tbl.Columns[column] = col
+      return nil
+    },
+  )
+  if err != nil {
+    return nil, err
+  }
+  rows, err = db.Query(ctx, `select tablename, indexname, indexdef
+from pg_indexes where schemaname = $1`, name)
+  if err != nil {
+    return nil, err
+  }
+  var index, def string
+  _, err = pgx.ForEachRow(rows, []any{&table, &index, &def}, func() error {
+    tbl, exist := schema.Tables[table]
+    if exist {
+      tbl.Indexes[index] = def
+    }
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+  return schema, nil
+}
+
+type SchemaDrift struct {
+  Kind string `json:"kind"`
+  Table string `json:"table"`
+  Name string `json:"name,omitempty"`
+  Expected string `json:"expected,omitempty"`
+  Actual string `json:"actual,omitempty"`
+}
+
+func (d SchemaDrift) String() string {
+  name := d.Table
+  if len(d.Name) > 0 {
+    name += "." + d.Name
+  }
+  if len(d.Expected) == 0 && len(d.Actual) == 0 {
+    return fmt.Sprintf("%s %s", d.Kind, name)
+  }
+  return fmt.Sprintf(
+    "%s %s: expected %q, got %q", d.Kind, name, d.Expected, d.Actual,
+  )
+}
+
+type SchemaDriftError struct {
+  Drifts []SchemaDrift
+}
+
+func (e *SchemaDriftError) Error() string {
+  drifts := make([]string, len(e.Drifts))
+  for i, drift := range e.Drifts {
+    drifts[i] = drift.String()
+  }
+  return "schema drift: " + strings.Join(drifts, "; ")
+}
+
+// Missing and extra names of the expected and actual maps in order
+func diffNames[V any](expected, actual map[string]V) ([]string, []string) {
+  var missing, extra []string
+  for _, name := range slices.Sorted(maps.Keys(expected)) {
+    _, exist := actual[name]
+    if !exist {
+      missing = append(missing, name)
+    }
+  }
+  for _, name := range slices.Sorted(maps.Keys(actual)) {
+    _, exist := expected[name]
+    if !exist {
+      extra = append(extra, name)
+    }
+  }
+  return missing, extra
+}
+
+func diffTable(name string, expected, actual Table) []SchemaDrift {
+  var drifts []SchemaDrift
+  missing, extra := diffNames(expected.Columns, actual.Columns)
+  for _, column := range missing {
+    drifts = append(
+      drifts, SchemaDrift{Kind: "missing_column", Table: name, Name: column},
+    )
+  }
+  for _, column := range extra {
+    drifts = append(
+      drifts, SchemaDrift{Kind: "extra_column", Table: name, Name: column},
+    )
+  }
+  for _, column := range slices.Sorted(maps.Keys(expected.Columns)) {
+    exp := expected.Columns[column]
+    act, exist := actual.Columns[column]
+    if !exist {
+      continue
+    }
+    if exp.Type != act.Type {
+      drifts = append(drifts, SchemaDrift{
+        Kind: "column_type", Table: name, Name: column,
+        Expected: exp.Type, Actual: act.Type,
+      })
+    }
+    if exp.Nullable != act.Nullable {
+      drifts = append(drifts, SchemaDrift{
+        Kind: "column_nullable", Table: name, Name: column,
+        Expected: fmt.Sprint(exp.Nullable), Actual: fmt.Sprint(act.Nullable),
+      })
+    }
+    if exp.Default != act.Default {
+      drifts = append(drifts, SchemaDrift{
+        Kind: "column_default", Table: name, Name: column,
+        Expected: exp.Default, Actual: act.Default,
+      })
+    }
+  }
+  missing, extra = diffNames(expected.Indexes, actual.Indexes)
+  for _, index := range missing {
+    drifts = append(
+      drifts, SchemaDrift{Kind: "missing_index", Table: name, Name: index},
+    )
+  }
+  for _, index := range extra {
+    drifts = append(
+      drifts, SchemaDrift{Kind: "extra_index", Table: name, Name: index},
+    )
+  }
+  for _, index := range slices.Sorted(maps.Keys(expected.Indexes)) {
+    act, exist := actual.Indexes[index]
+    if exist && act != expected.Indexes[index] {
+      drifts = append(drifts, SchemaDrift{
+        Kind: "index_definition", Table: name, Name: index,
+        Expected: expected.Indexes[index], Actual: act,
+      })
+    }
+  }
+  return drifts
+}
+
+// Structured differences between the expected and actual schemas
+func DiffSchema(expected, actual *Schema) []SchemaDrift {
+  var drifts []SchemaDrift
+  missing, extra := diffNames(expected.Tables, actual.Tables)
+  for _, table := range missing {
+    drifts = append(drifts, SchemaDrift{Kind: "missing_table", Table: table})
+  }
+  for _, table := range extra {
+    drifts = append(drifts, SchemaDrift{Kind: "extra_table", Table: table})
+  }
+  for _, table := range slices.Sorted(maps.Keys(expected.Tables)) {
+    act, exist := actual.Tables[table]
+    if exist {
+      drifts = append(drifts, diffTable(table, expected.Tables[table], act)...)
+    }
+  }
+  return drifts
+}
+
+// Compares the live schema with the snapshot, returns *SchemaDriftError on
+// drift
+func VerifySchema(
+  ctx context.Context, db QueryExecutor, expected *Schema,
+) error {
+  name := expected.Name
+  if len(name) == 0 {
+    name = "public"
+  }
+  actual, err := ReadSchema(ctx, db, name)
+  if err != nil {
+    return err
+  }
+  drifts := DiffSchema(expected, actual)
+  if len(drifts) > 0 {
+    return &SchemaDriftError{Drifts: drifts}
+  }
+  return nil
+}
+
+// Degraded check for health endpoints next to PoolCheck
+func SchemaCheck(
+  db QueryExecutor, expected *Schema,
+) func(ctx context.Context) error {
+  return func(ctx context.Context) error {
+    return VerifySchema(ctx, db, expected)
+  }
+}
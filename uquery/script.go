@@ -0,0 +1,145 @@
+package uquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+// Nested transactions are savepoints of the script transaction, which is
+// committed or rolled back only by RunScript
+type scriptTx struct {
+  pgx.Tx
+  action string
+  statements *int
+  nested bool
+}
+
+var errScriptTx = errors.New("script transaction is committed by RunScript")
+
+func statementSummary(sql string) string {
+  sql = strings.Join(strings.Fields(sql), " ")
+  if len(sql) > 80 {
+    sql = sql[:80] + "..."
+  }
+  return sql
+}
+
+func (t *scriptTx) log(err error, start time.Time, result, sql string) {
+  *t.statements++
+  userv.LogAction(
+    t.action, err, start, fmt.Sprintf("#%d", *t.statements), result,
+    statementSummary(sql),
+  )
+}
+
+func (t *scriptTx) Begin(ctx context.Context) (pgx.Tx, error) {
+  tx, err := t.Tx.Begin(ctx)
+  if err != nil {
+    return nil, err
+  }
+  return &scriptTx{
+    Tx: tx, action: t.action, statements: t.statements, nested: true,
+  }, nil
+}
+
+func (t *scriptTx) Commit(ctx context.Context) error {
+  if !t.nested {
+    return errScriptTx
+  }
+  return t.Tx.Commit(ctx)
+}
+
+func (t *scriptTx) Rollback(ctx context.Context) error {
+  if !t.nested {
+    return errScriptTx
+  }
+  return t.Tx.Rollback(ctx)
+}
+
+// Logs the affected row count of every statement
+func (t *scriptTx) Exec(
+  ctx context.Context, sql string, args ...any,
+) (pgconn.CommandTag, error) {
+  start := time.Now()
+  tag, err := t.Tx.Exec(ctx, sql, args...)
+  t.log(err, start, tag.String(), sql)
+  return tag, err
+}
+
+func (t *scriptTx) Query(
+  ctx context.Context, sql string, args ...any,
+) (pgx.Rows, error) {
+  start := time.Now()
+  rows, err := t.Tx.Query(ctx, sql, args...)
+  t.log(err, start, "QUERY", sql)
+  return rows, err
+}
+
+func (t *scriptTx) QueryRow(
+  ctx context.Context, sql string, args ...any,
+) pgx.Row {
+  start := time.Now()
+  row := t.Tx.QueryRow(ctx, sql, args...)
+  t.log(nil, start, "QUERY", sql)
+  return row
+}
+
+func (t *scriptTx) CopyFrom(
+  ctx context.Context, table pgx.Identifier, columns []string,
+  src pgx.CopyFromSource,
+) (int64, error) {
+  start := time.Now()
+  n, err := t.Tx.CopyFrom(ctx, table, columns, src)
+  t.log(err, start, fmt.Sprintf("COPY %d", n), "copy " + table.Sanitize())
+  return n, err
+}
+
+func (t *scriptTx) SendBatch(
+  ctx context.Context, batch *pgx.Batch,
+) pgx.BatchResults {
+  start := time.Now()
+  res := t.Tx.SendBatch(ctx, batch)
+  var sqls []string
+  for _, query := range batch.QueuedQueries {
+    sqls = append(sqls, query.SQL)
+  }
+  t.log(
+    nil, start, fmt.Sprintf("BATCH %d", batch.Len()), strings.Join(sqls, "; "),
+  )
+  return res
+}
+
+// Runs the script in a transaction committed on success. With dryRun the
+// transaction is always rolled back to rehearse backfills against production
+// replicas, the would-be row counts are logged per statement. The script
+// must not commit or roll back tx itself, tx.Begin opens a savepoint
+func RunScript(
+  ctx context.Context, pool *pgxpool.Pool, dryRun bool,
+  script func(ctx context.Context, tx pgx.Tx) error,
+) error {
+  tx, err := pool.Begin(ctx)
+  if err != nil {
+    return err
+  }
+  var statements int
+  stx := &scriptTx{
+    Tx: &trackedTx{Tx: tx}, action: "exec", statements: &statements,
+  }
+  if dryRun {
+    stx.action = "dry-run"
+  }
+  err = script(ctx, stx)
+  if err != nil || dryRun {
+    rbErr := tx.Rollback(context.WithoutCancel(ctx))
+    return errors.Join(err, rbErr)
+  }
+  return tx.Commit(ctx)
+}
@@ -0,0 +1,76 @@
+package urand
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// Splits n by the quotas with the largest remainder method, counts sum to n
+func quotaCounts(n int, quotas map[string]float64) (map[string]int, error) {
+  var total float64
+  for name, quota := range quotas {
+    if quota < 0 || math.IsNaN(quota) || math.IsInf(quota, 0) {
+      return nil, fmt.Errorf("invalid quota %s %v", name, quota)
+    }
+    total += quota
+  }
+  if total == 0 {
+    return nil, fmt.Errorf("empty quotas")
+  }
+  names := slices.Sorted(maps.Keys(quotas))
+  counts := make(map[string]int, len(quotas))
+  remainders := make(map[string]float64, len(quotas))
+  assigned := 0
+  for _, name := range names {
+    exact := float64(n) * quotas[name] / total
+    counts[name] = int(exact)
+    remainders[name] = exact - float64(counts[name])
+    assigned += counts[name]
+  }
+  slices.SortStableFunc(names, func(a, b string) int {
+    switch {
+    case remainders[a] > remainders[b]:
+      return -1
+    case remainders[a] < remainders[b]:
+      return 1
+    }
+    return 0
+  })
+  for i := range n - assigned {
+    counts[names[i]]++
+  }
+  return counts, nil
+}
+
+// Generates n items with exact category quotas e.g. {"paid": 0.7, "pending":
+// 0.2, "failed": 0.1} in shuffled order, quotas are relative weights
+func RandBatch[T any](
+  n int, quotas map[string]float64, gens map[string]func() T,
+) ([]T, error) {
+  if n < 0 {
+    return nil, fmt.Errorf("invalid batch size %d", n)
+  }
+  for name := range quotas {
+    _, exist := gens[name]
+    if !exist {
+      return nil, fmt.Errorf("missing generator %s", name)
+    }
+  }
+  counts, err := quotaCounts(n, quotas)
+  if err != nil {
+    return nil, err
+  }
+  items := make([]T, 0, n)
+  for name, count := range counts {
+    for range count {
+      items = append(items, gens[name]())
+    }
+  }
+  for i := len(items) - 1; i > 0; i-- {
+    j := RandInt(0, i + 1)
+    items[i], items[j] = items[j], items[i]
+  }
+  return items, nil
+}
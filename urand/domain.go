@@ -0,0 +1,48 @@
+package urand
+
+import (
+	"sync/atomic"
+)
+
+// RFC 2606 documentation domains that never deliver mail
+var reservedDomains = []string{
+  "example.com", "example.org", "example.net", "mail.test", "mail.example",
+  "mail.invalid",
+}
+
+var reservedOnly atomic.Bool
+
+// Forces reserved domains for all RandEmail and RandURL calls e.g. in staging
+// jobs that may send notifications
+func ReservedOnly(on bool) {
+  reservedOnly.Store(on)
+}
+
+type domainConfig struct {
+  domains []string
+}
+
+type domainOption func(cfg *domainConfig)
+
+func ReservedDomains() domainOption {
+  return func(cfg *domainConfig) {
+    cfg.domains = reservedDomains
+  }
+}
+
+func DomainPool(domains ...string) domainOption {
+  return func(cfg *domainConfig) {
+    cfg.domains = domains
+  }
+}
+
+func randDomain(defaults []string, opts ...domainOption) string {
+  cfg := &domainConfig{domains: defaults}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  if reservedOnly.Load() || len(cfg.domains) == 0 {
+    cfg.domains = reservedDomains
+  }
+  return RandFrom(cfg.domains...)
+}
@@ -0,0 +1,49 @@
+package urand
+
+import (
+	"io"
+	"time"
+)
+
+func randFloat() float64 {
+  return float64(RandInt(0, 1 << 30)) / (1 << 30)
+}
+
+func Jitter(base, spread time.Duration) time.Duration {
+  if spread <= 0 {
+    return base
+  }
+  d := base - spread + time.Duration(RandInt(0, int(2 * spread) + 1))
+  return max(d, 0)
+}
+
+func Flaky(errRate float64, err error) error {
+  if randFloat() < errRate {
+    return err
+  }
+  return nil
+}
+
+type slowReader struct {
+  r io.Reader
+  bytesPerSec int
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+  chunk := max(s.bytesPerSec / 10, 1) // 100ms worth of bytes
+  if len(p) > chunk {
+    p = p[:chunk]
+  }
+  start := time.Now()
+  n, err := s.r.Read(p)
+  delay := time.Duration(n) * time.Second / time.Duration(s.bytesPerSec)
+  time.Sleep(delay - time.Since(start))
+  return n, err
+}
+
+func SlowReader(r io.Reader, bytesPerSec int) io.Reader {
+  if bytesPerSec <= 0 {
+    return r
+  }
+  return &slowReader{r: r, bytesPerSec: bytesPerSec}
+}
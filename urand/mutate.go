@@ -0,0 +1,116 @@
+package urand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Object key or array index path to a JSON value
+type jsonPath []any
+
+func jsonPaths(val any, path jsonPath, paths *[]jsonPath) {
+  switch v := val.(type) {
+  case map[string]any:
+    for key, item := range v {
+      itemPath := append(append(jsonPath{}, path...), key)
+      *paths = append(*paths, itemPath)
+      jsonPaths(item, itemPath, paths)
+    }
+  case []any:
+    for i, item := range v {
+      itemPath := append(append(jsonPath{}, path...), i)
+      *paths = append(*paths, itemPath)
+      jsonPaths(item, itemPath, paths)
+    }
+  }
+}
+
+func jsonParent(doc any, path jsonPath) any {
+  for _, step := range path[:len(path) - 1] {
+    switch v := doc.(type) {
+    case map[string]any:
+      doc = v[step.(string)]
+    case []any:
+      doc = v[step.(int)]
+    }
+  }
+  return doc
+}
+
+// Wrong type, overlong string, negative number or null
+func mutateValue(val any) any {
+  switch v := val.(type) {
+  case string:
+    return RandFrom[any](
+      strings.Repeat(v + "x", 10000 / (len(v) + 1) + 1), json.Number("1"),
+      true, map[string]any{}, "",
+    )
+  case json.Number:
+    neg := json.Number("-" + strings.TrimPrefix(v.String(), "-"))
+    if neg == "-0" {
+      neg = "-1"
+    }
+    return RandFrom[any](neg, v.String(), json.Number("1e309"), false)
+  case bool:
+    return RandFrom[any](json.Number("1"), "true")
+  case map[string]any:
+    return RandFrom[any]([]any{v}, "object")
+  case []any:
+    return RandFrom[any](map[string]any{}, "array", []any{nil})
+  }
+  return RandFrom[any](json.Number("0"), "null", map[string]any{})
+}
+
+func mutateJSON(doc any, path jsonPath) {
+  parent := jsonParent(doc, path)
+  switch p := parent.(type) {
+  case map[string]any:
+    key := path[len(path) - 1].(string)
+    // Missing field or null
+    switch RandInt(0, 4) {
+    case 0:
+      delete(p, key)
+    case 1:
+      p[key] = nil
+    default:
+      p[key] = mutateValue(p[key])
+    }
+  case []any:
+    i := path[len(path) - 1].(int)
+    p[i] = mutateValue(p[i])
+  }
+}
+
+// Structurally plausible but subtly broken variants of the valid payload
+// with one field missing, null, of a wrong type, overlong or negative
+func MutateJSON(valid []byte, n int) ([][]byte, error) {
+  decode := func() (any, error) {
+    dec := json.NewDecoder(bytes.NewReader(valid))
+    dec.UseNumber()
+    var doc any
+    err := dec.Decode(&doc)
+    return doc, err
+  }
+  doc, err := decode()
+  if err != nil {
+    return nil, err
+  }
+  var paths []jsonPath
+  jsonPaths(doc, nil, &paths)
+  if len(paths) == 0 {
+    return nil, errors.New("JSON payload has no fields to mutate")
+  }
+  variants := make([][]byte, 0, n)
+  for range n {
+    doc, _ := decode()
+    mutateJSON(doc, paths[RandInt(0, len(paths))])
+    variant, err := json.Marshal(doc)
+    if err != nil {
+      return nil, err
+    }
+    variants = append(variants, variant)
+  }
+  return variants, nil
+}
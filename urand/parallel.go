@@ -0,0 +1,41 @@
+package urand
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Contiguous chunk of [0, n) for the worker
+func chunk(n, workers, w int) (int, int) {
+  size := (n + workers - 1) / workers
+  return min(w * size, n), min((w + 1) * size, n)
+}
+
+// Generates n items across workers goroutines, gen must be concurrency-safe
+// e.g. built on the package generators
+func Parallel[T any](n, workers int, gen func(i int) T) []T {
+  return ParallelSeeded(n, workers, 0, func(i int, rnd *rand.Rand) T {
+    return gen(i)
+  })
+}
+
+// Each worker gets an independent source seeded from the master seed and the
+// worker index, so the output is deterministic for the same n, workers, seed
+func ParallelSeeded[T any](
+  n, workers int, seed uint64, gen func(i int, rnd *rand.Rand) T,
+) []T {
+  workers = max(min(workers, n), 1)
+  items := make([]T, n)
+  var wg sync.WaitGroup
+  for w := range workers {
+    wg.Go(func() {
+      rnd := rand.New(rand.NewPCG(seed, uint64(w)))
+      from, to := chunk(n, workers, w)
+      for i := from; i < to; i++ {
+        items[i] = gen(i, rnd)
+      }
+    })
+  }
+  wg.Wait()
+  return items
+}
@@ -144,42 +144,55 @@ func RandFrom[T any](items ...T) T {
   return items[i]
 }
 
-func RandDate(a, b time.Time) time.Time {
-  t := time.Unix(int64(RandInt(int(a.Unix()), int(b.Unix()))), 0)
-  date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.UTC().Location())
-  return date
+func RandDate(a, b time.Time, opts ...timeOption) time.Time {
+  opts = append([]timeOption{Granularity(24 * time.Hour)}, opts...)
+  return randTime(a, b, opts...)
 }
 
-func RandDateP(a, b time.Time) *time.Time {
-  return timeP(RandDate(a, b))
+func RandDateP(a, b time.Time, opts ...timeOption) *time.Time {
+  return timeP(RandDate(a, b, opts...))
 }
 
-func RandTime(a, b time.Time) time.Time {
-  return time.Unix(int64(RandInt(int(a.Unix()), int(b.Unix()))), 0).UTC()
+func RandTime(a, b time.Time, opts ...timeOption) time.Time {
+  opts = append([]timeOption{Granularity(time.Second)}, opts...)
+  return randTime(a, b, opts...)
 }
 
-func RandTimeP(a, b time.Time) *time.Time {
-  return timeP(RandTime(a, b))
+func RandTimeP(a, b time.Time, opts ...timeOption) *time.Time {
+  return timeP(RandTime(a, b, opts...))
 }
 
-func RandEmail() string {
-  domain := RandFrom("mail.com", "email.com", "gmail.com")
+// A random time in the past d
+func RandTimeSince(d time.Duration, opts ...timeOption) time.Time {
+  now := time.Now()
+  return RandTime(now.Add(-d), now, opts...)
+}
+
+// A random time in the next d
+func RandTimeWithin(d time.Duration, opts ...timeOption) time.Time {
+  now := time.Now()
+  return RandTime(now, now.Add(d), opts...)
+}
+
+func RandEmail(opts ...domainOption) string {
+  domain := randDomain([]string{"mail.com", "email.com", "gmail.com"}, opts...)
   email := fmt.Sprintf("%s@%s", RandAbc(8), domain)
   return strings.ToLower(email)
 }
 
-func RandEmailP() *string {
-  return stringP(RandEmail())
+func RandEmailP(opts ...domainOption) *string {
+  return stringP(RandEmail(opts...))
 }
 
-func RandURL() string {
-  domain := RandFrom("com", "org", "net")
+// The host is a random subdomain of the domain
+func RandURL(opts ...domainOption) string {
+  domain := randDomain([]string{"com", "org", "net"}, opts...)
   url := fmt.Sprintf("https://%s.%s/%s", RandStr(8), domain, RandStr(8))
   return strings.ToLower(url)
 }
 
-func RandURLP() *string {
-  return stringP(RandURL())
+func RandURLP(opts ...domainOption) *string {
+  return stringP(RandURL(opts...))
 }
 
 func RandIP() string {
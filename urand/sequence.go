@@ -0,0 +1,25 @@
+package urand
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+var deterministic atomic.Bool
+
+// Switches Sequence generators to predictable IDs e.g. for udump.Golden tests
+func Deterministic(on bool) {
+  deterministic.Store(on)
+}
+
+// Returns cus_0001, cus_0002... in deterministic mode, random cus_<14 alnum>
+// IDs otherwise
+func Sequence(prefix string) func() string {
+  var n atomic.Int64
+  return func() string {
+    if deterministic.Load() {
+      return fmt.Sprintf("%s%04d", prefix, n.Add(1))
+    }
+    return prefix + RandStr(14)
+  }
+}
@@ -0,0 +1,89 @@
+package urand
+
+import (
+	"time"
+)
+
+type timeConfig struct {
+  loc *time.Location
+  granularity time.Duration
+  weekdays bool
+  businessHours bool
+}
+
+type timeOption func(cfg *timeConfig)
+
+func TimeZone(loc *time.Location) timeOption {
+  return func(cfg *timeConfig) {
+    cfg.loc = loc
+  }
+}
+
+// Truncation to the day, hour, minute or second in the selected time zone
+func Granularity(granularity time.Duration) timeOption {
+  return func(cfg *timeConfig) {
+    cfg.granularity = granularity
+  }
+}
+
+func Weekdays() timeOption {
+  return func(cfg *timeConfig) {
+    cfg.weekdays = true
+  }
+}
+
+// Mon-Fri 09:00-17:00 in the selected time zone
+func BusinessHours() timeOption {
+  return func(cfg *timeConfig) {
+    cfg.weekdays = true
+    cfg.businessHours = true
+  }
+}
+
+func truncateIn(t time.Time, granularity time.Duration) time.Time {
+  y, m, d := t.Date()
+  switch {
+  case granularity >= 24 * time.Hour:
+    return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+  case granularity >= time.Hour:
+    return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+  case granularity >= time.Minute:
+    return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location())
+  default:
+    return t.Truncate(max(granularity, time.Nanosecond))
+  }
+}
+
+func randTimeCandidate(a, b time.Time, cfg *timeConfig) time.Time {
+  // Nanoseconds keep sub-second ranges valid
+  t := a.Add(time.Duration(RandInt(0, int(b.Sub(a))))).In(cfg.loc)
+  if cfg.businessHours {
+    y, m, d := t.Date()
+    seconds := RandInt(9 * 3600, 17 * 3600)
+    t = time.Date(y, m, d, 0, 0, seconds, 0, cfg.loc)
+  }
+  return truncateIn(t, cfg.granularity)
+}
+
+func randTime(a, b time.Time, opts ...timeOption) time.Time {
+  cfg := &timeConfig{loc: time.UTC, granularity: time.Second}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  if !b.After(a) {
+    return truncateIn(a.In(cfg.loc), cfg.granularity)
+  }
+  var t time.Time
+  for range 1000 {
+    t = randTimeCandidate(a, b, cfg)
+    weekday := t.Weekday()
+    if cfg.weekdays && (weekday == time.Saturday || weekday == time.Sunday) {
+      continue
+    }
+    if cfg.businessHours && (t.Before(a) || !t.Before(b)) {
+      continue
+    }
+    return t
+  }
+  return t
+}
@@ -0,0 +1,37 @@
+package ureq
+
+import (
+	"fmt"
+)
+
+const decodeBodyLimit = 4096
+
+type DecodeError struct {
+  StatusCode int
+  ContentType string
+  // Raw response body capped to decodeBodyLimit bytes
+  Body []byte
+  Truncated bool
+  Err error
+}
+
+func (e *DecodeError) Error() string {
+  return fmt.Sprintf(
+    "decode %d %s response: %s: %q", e.StatusCode, e.ContentType, e.Err, e.Body,
+  )
+}
+
+func (e *DecodeError) Unwrap() error {
+  return e.Err
+}
+
+func newDecodeError(
+  statusCode int, contType string, body []byte, err error,
+) error {
+  derr := &DecodeError{StatusCode: statusCode, ContentType: contType, Err: err}
+  if len(body) > decodeBodyLimit {
+    body, derr.Truncated = body[:decodeBodyLimit], true
+  }
+  derr.Body = append([]byte(nil), body...)
+  return derr
+}
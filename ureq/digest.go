@@ -0,0 +1,47 @@
+package ureq
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"strings"
+)
+
+// Supported digest headers with base64-encoded values
+func newDigest(header string) (hash.Hash, error) {
+  switch strings.ToLower(header) {
+  case "content-md5":
+    return md5.New(), nil
+  case "x-amz-checksum-sha1":
+    return sha1.New(), nil
+  case "x-amz-checksum-sha256":
+    return sha256.New(), nil
+  case "x-amz-checksum-crc32":
+    return crc32.NewIEEE(), nil
+  case "x-amz-checksum-crc32c":
+    return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+  default:
+    return nil, fmt.Errorf("unsupported digest header %s", header)
+  }
+}
+
+func verifyDigest(res *http.Response, header string, digest hash.Hash) error {
+  expected := res.Header.Get(header)
+  if len(expected) == 0 {
+    return fmt.Errorf("digest verification: missing %s header", header)
+  }
+  actual := base64.StdEncoding.EncodeToString(digest.Sum(nil))
+  if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+    return fmt.Errorf(
+      "digest verification: %s mismatch: expected %s, got %s",
+      header, expected, actual,
+    )
+  }
+  return nil
+}
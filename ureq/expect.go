@@ -0,0 +1,46 @@
+package ureq
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Non-success response returned as an error, the body is decoded into E
+type HTTPError[E any] struct {
+  StatusCode int
+  Header http.Header
+  Body E
+  // Raw response body capped to decodeBodyLimit bytes
+  Raw []byte
+}
+
+func (e *HTTPError[E]) Error() string {
+  return fmt.Sprintf("HTTP %d: %q", e.StatusCode, e.Raw)
+}
+
+func newHTTPError[E any](res *http.Response, body []byte) error {
+  herr := &HTTPError[E]{StatusCode: res.StatusCode, Header: res.Header}
+  // An undecodable body keeps the zero E, the raw body is still available
+  _ = json.Unmarshal(body, &herr.Body)
+  herr.Raw = append([]byte(nil), body[:min(len(body), decodeBodyLimit)]...)
+  return herr
+}
+
+// Success status codes, any other status fails the request with
+// *HTTPError[json.RawMessage] unless ErrJSONAs selects the error body type
+func ReqExpect(codes ...int) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.success = codes
+    if cfg.errAs == nil {
+      cfg.errAs = newHTTPError[json.RawMessage]
+    }
+  }
+}
+
+// Non-success status fails the request with *HTTPError[E], use errors.As
+func ErrJSONAs[E any]() requestOption {
+  return func(cfg *requestConfig) {
+    cfg.errAs = newHTTPError[E]
+  }
+}
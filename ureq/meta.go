@@ -0,0 +1,124 @@
+package ureq
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+)
+
+type metaKey struct{}
+
+func WithMeta(ctx context.Context, key, value string) context.Context {
+  meta := maps.Clone(Metas(ctx))
+  if meta == nil {
+    meta = make(map[string]string)
+  }
+  meta[key] = value
+  return context.WithValue(ctx, metaKey{}, meta)
+}
+
+func withMetas(ctx context.Context, metas map[string]string) context.Context {
+  if len(metas) == 0 {
+    return ctx
+  }
+  meta := maps.Clone(Metas(ctx))
+  if meta == nil {
+    meta = make(map[string]string, len(metas))
+  }
+  maps.Copy(meta, metas)
+  return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// Request metadata e.g. MetaValue(req.Context(), "operation")
+func MetaValue(ctx context.Context, key string) string {
+  return Metas(ctx)[key]
+}
+
+func Metas(ctx context.Context) map[string]string {
+  meta, _ := ctx.Value(metaKey{}).(map[string]string)
+  return meta
+}
+
+// Request metadata e.g. operation, tenant, criticality visible to
+// interceptors, observers and retry profiles through the request context
+func Meta(key, value string) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.meta[key] = value
+  }
+}
+
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// Wraps every attempt, the first interceptor is the outermost
+type Interceptor func(
+  req *http.Request, next RoundTrip,
+) (*http.Response, error)
+
+// Called after every attempt e.g. for metrics
+type Observer func(
+  req *http.Request, res *http.Response, err error, elapsed time.Duration,
+)
+
+func Intercept(interceptors ...Interceptor) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.interceptors = append(cfg.interceptors, interceptors...)
+  }
+}
+
+func Observe(observers ...Observer) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.observers = append(cfg.observers, observers...)
+  }
+}
+
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+  next := RoundTrip(c.client.Do)
+  for i := len(c.interceptors) - 1; i >= 0; i-- {
+    interceptor, inner := c.interceptors[i], next
+    next = func(req *http.Request) (*http.Response, error) {
+      return interceptor(req, inner)
+    }
+  }
+  start := time.Now()
+  res, err := next(req)
+  elapsed := time.Since(start)
+  for _, observe := range c.observers {
+    observe(req, res, err, elapsed)
+  }
+  return res, err
+}
+
+type selectiveRetry struct {
+  match func(req *http.Request) bool
+  profile RetryProfile
+}
+
+// Retries with the profile only matching requests e.g.
+// RetryWhen(MetaIs("criticality", "high"), StripeRetry(2))
+func RetryWhen(
+  match func(req *http.Request) bool, profile RetryProfile,
+) RetryProfile {
+  return &selectiveRetry{match: match, profile: profile}
+}
+
+func MetaIs(key, value string) func(req *http.Request) bool {
+  return func(req *http.Request) bool {
+    return MetaValue(req.Context(), key) == value
+  }
+}
+
+func (s *selectiveRetry) Prepare(req *http.Request) {
+  if s.match(req) {
+    s.profile.Prepare(req)
+  }
+}
+
+func (s *selectiveRetry) Retry(
+  req *http.Request, attempt int, res *http.Response, err error,
+) (time.Duration, bool) {
+  if !s.match(req) {
+    return 0, false
+  }
+  return s.profile.Retry(req, attempt, res, err)
+}
@@ -0,0 +1,172 @@
+package ureq
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Time layouts by tag option, RFC 3339 by default
+func formatTime(t time.Time, opts []string) string {
+  for _, opt := range opts {
+    switch opt {
+    case "unix":
+      return strconv.FormatInt(t.Unix(), 10)
+    case "unixmilli":
+      return strconv.FormatInt(t.UnixMilli(), 10)
+    case "date":
+      return t.Format(time.DateOnly)
+    }
+  }
+  return t.Format(time.RFC3339)
+}
+
+func formatScalar(val reflect.Value, opts []string) (string, error) {
+  if val.Type() == timeType {
+    return formatTime(val.Interface().(time.Time), opts), nil
+  }
+  if val.CanInterface() {
+    text, ok := val.Interface().(encoding.TextMarshaler)
+    if ok {
+      btext, err := text.MarshalText()
+      return string(btext), err
+    }
+  }
+  switch val.Kind() {
+  case reflect.String:
+    return val.String(), nil
+  case reflect.Bool:
+    return strconv.FormatBool(val.Bool()), nil
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return strconv.FormatInt(val.Int(), 10), nil
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+    reflect.Uint64:
+    return strconv.FormatUint(val.Uint(), 10), nil
+  case reflect.Float32, reflect.Float64:
+    return strconv.FormatFloat(val.Float(), 'f', -1, 64), nil
+  }
+  return "", fmt.Errorf("unsupported parameter type %s", val.Type())
+}
+
+func encodeQuery(query url.Values, val reflect.Value) error {
+  typ := val.Type()
+  for i := range typ.NumField() {
+    field := typ.Field(i)
+    // Embedded unexported structs still promote their fields
+    if !field.IsExported() && !field.Anonymous {
+      continue
+    }
+    tag, exist := field.Tag.Lookup("url")
+    if !exist {
+      tag = field.Tag.Get("query")
+    }
+    name, optStr, _ := strings.Cut(tag, ",")
+    opts := strings.Split(optStr, ",")
+    if name == "-" {
+      continue
+    }
+    fval := val.Field(i)
+    for fval.Kind() == reflect.Pointer {
+      if fval.IsNil() {
+        break
+      }
+      fval = fval.Elem()
+    }
+    if fval.Kind() == reflect.Pointer {
+      continue // nil
+    }
+    if field.Anonymous && len(name) == 0 && fval.Kind() == reflect.Struct {
+      err := encodeQuery(query, fval)
+      if err != nil {
+        return err
+      }
+      continue
+    }
+    if !field.IsExported() {
+      continue
+    }
+    if len(name) == 0 {
+      name = field.Name
+    }
+    if fval.IsZero() && slices.Contains(opts, "omitempty") {
+      continue
+    }
+    if fval.Kind() == reflect.Slice || fval.Kind() == reflect.Array {
+      items := make([]string, 0, fval.Len())
+      for j := range fval.Len() {
+        item, err := formatScalar(fval.Index(j), opts)
+        if err != nil {
+          return fmt.Errorf("%s: %w", name, err)
+        }
+        items = append(items, item)
+      }
+      if slices.Contains(opts, "comma") {
+        query.Set(name, strings.Join(items, ","))
+      } else {
+        query[name] = items
+      }
+      continue
+    }
+    item, err := formatScalar(fval, opts)
+    if err != nil {
+      return fmt.Errorf("%s: %w", name, err)
+    }
+    query.Set(name, item)
+  }
+  return nil
+}
+
+// Encodes struct fields by url or query tags with omitempty, comma-joined
+// slices and unix, unixmilli or date times, repeated keys by default
+func QueryStruct(v any) requestOption {
+  return func(cfg *requestConfig) {
+    val := reflect.ValueOf(v)
+    for val.Kind() == reflect.Pointer && !val.IsNil() {
+      val = val.Elem()
+    }
+    if val.Kind() != reflect.Struct {
+      cfg.err = fmt.Errorf("query struct: expected struct, got %T", v)
+      return
+    }
+    err := encodeQuery(cfg.query, val)
+    if err != nil {
+      cfg.err = fmt.Errorf("query struct: %w", err)
+    }
+  }
+}
+
+var pathParamRe = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Fills {name} placeholders with path-escaped params, missing params fail
+// the request e.g. Path("/users/{id}", map[string]any{"id": id})
+func Path(template string, params map[string]any) requestOption {
+  return func(cfg *requestConfig) {
+    var err error
+    fill := func(ph string) string {
+      name := ph[1:len(ph) - 1]
+      param, exist := params[name]
+      if !exist || param == nil {
+        err = fmt.Errorf("path %s: missing %s", template, name)
+        return ph
+      }
+      str, ferr := formatScalar(reflect.ValueOf(param), nil)
+      if ferr != nil {
+        err = fmt.Errorf("path %s: %s: %w", template, name, ferr)
+        return ph
+      }
+      return url.PathEscape(str)
+    }
+    cfg.url = pathParamRe.ReplaceAllStringFunc(template, fill)
+    if err != nil {
+      cfg.err = err
+    }
+  }
+}
@@ -0,0 +1,69 @@
+package ureq
+
+import (
+	"context"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/urand"
+)
+
+// Fetches the page after the cursor, returns the next cursor and whether the
+// page was empty. An empty next cursor keeps the current one
+type PollFunc func(
+  ctx context.Context, c *Client, cursor string,
+) (next string, empty bool, err error)
+
+type pollConfig struct {
+  cursor string
+}
+
+type pollOption func(cfg *pollConfig)
+
+// Resumes polling from a persisted cursor
+func PollFrom(cursor string) pollOption {
+  return func(cfg *pollConfig) {
+    cfg.cursor = cursor
+  }
+}
+
+// Polls until the context is canceled or fn fails and returns the last
+// cursor. Non-empty pages are drained without delay, empty pages double the
+// delay from interval up to maxInterval
+func (c *Client) Poll(
+  ctx context.Context, interval, maxInterval time.Duration, fn PollFunc,
+  opts ...pollOption,
+) (string, error) {
+  cfg := &pollConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  cursor, delay := cfg.cursor, time.Duration(0)
+  timer := time.NewTimer(0)
+  defer timer.Stop()
+  for {
+    select {
+    case <-ctx.Done():
+      return cursor, nil
+    case <-timer.C:
+    }
+    next, empty, err := fn(ctx, c, cursor)
+    if err != nil {
+      if ctx.Err() != nil {
+        return cursor, nil
+      }
+      return cursor, err
+    }
+    if len(next) > 0 {
+      cursor = next
+    }
+    switch {
+    case !empty:
+      delay = 0
+    case delay == 0:
+      delay = interval
+    default:
+      delay = min(delay * 2, maxInterval)
+    }
+    timer.Reset(urand.Jitter(delay, delay / 10))
+  }
+}
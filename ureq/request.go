@@ -3,13 +3,17 @@ package ureq
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/volodymyrprokopyuk/go-util/udump"
@@ -55,12 +59,26 @@ func TimeP(t time.Time) *time.Time {
 type Client struct {
   client *http.Client
   baseURL string
+  retry RetryProfile
+  interceptors []Interceptor
+  observers []Observer
+  warmupConns int
+  warmupMethod string
+  header map[string]string
 }
 
 type clientConfig struct {
   baseURL string
   timeout time.Duration
   keepAlive bool
+  maxIdleConns int
+  tls *tls.Config
+  retry RetryProfile
+  interceptors []Interceptor
+  observers []Observer
+  warmupConns int
+  warmupMethod string
+  header map[string]string
 }
 
 type clientOption func (cfg *clientConfig)
@@ -83,16 +101,47 @@ func KeepAlive(keepAlive bool) clientOption {
   }
 }
 
+// Header sent with every request unless the request sets it
+func DefaultHeader(key, value string) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.header[http.CanonicalHeaderKey(key)] = value
+  }
+}
+
+func UserAgent(userAgent string) clientOption {
+  return DefaultHeader("User-Agent", userAgent)
+}
+
+// Idle connections kept per host, 2 by default
+func MaxIdleConnsPerHost(conns int) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.maxIdleConns = conns
+  }
+}
+
+// e.g. Retry(StripeRetry(2)) or Retry(AWSRetry(3))
+func Retry(profile RetryProfile) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.retry = profile
+  }
+}
+
 func NewClient(opts ...clientOption) *Client {
   cfg := &clientConfig{
     timeout: 5 * time.Second,
     keepAlive: true,
+    warmupMethod: http.MethodHead,
+    header: make(map[string]string),
   }
   for _, opt := range opts {
     opt(cfg)
   }
   trn := &http.Transport{
     DisableKeepAlives: !cfg.keepAlive,
+    MaxIdleConnsPerHost: cfg.maxIdleConns,
+    TLSClientConfig: cfg.tls,
+    // A custom TLS config disables HTTP/2 unless forced
+    ForceAttemptHTTP2: cfg.tls != nil,
   }
   cln := &http.Client{
     Transport: trn,
@@ -101,6 +150,12 @@ func NewClient(opts ...clientOption) *Client {
   return &Client{
     client: cln,
     baseURL: cfg.baseURL,
+    retry: cfg.retry,
+    interceptors: cfg.interceptors,
+    observers: cfg.observers,
+    warmupConns: cfg.warmupConns,
+    warmupMethod: cfg.warmupMethod,
+    header: cfg.header,
   }
 }
 
@@ -108,12 +163,21 @@ type requestConfig struct {
   err error
   trace bool
   url string
-  query map[string]string
+  query url.Values
   header map[string]string
   reqBytes []byte
   resValue any
   resError any
+  success []int
+  errAs func(res *http.Response, body []byte) error
   resBytes *[]byte
+  resSpool *io.ReadSeekCloser
+  spoolThreshold int64
+  resStream func(body io.Reader) error
+  resFile string
+  digestHeader string
+  propagateDeadline bool
+  meta map[string]string
 }
 
 type requestOption func (cfg *requestConfig)
@@ -132,13 +196,13 @@ func URL(val string) requestOption {
 
 func Query(key, value string) requestOption {
   return func(cfg *requestConfig) {
-    cfg.query[key] = value
+    cfg.query.Set(key, value)
   }
 }
 
 func Header(key, value string) requestOption {
   return func(cfg *requestConfig) {
-    cfg.header[key] = value
+    cfg.header[http.CanonicalHeaderKey(key)] = value
   }
 }
 
@@ -226,6 +290,30 @@ func ResBytes(value *[]byte) requestOption {
   }
 }
 
+func ResSpool(threshold int64, body *io.ReadSeekCloser) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.spoolThreshold = threshold
+    cfg.resSpool = body
+  }
+}
+
+// Fails the request when the body of a successful response does not match the
+// digest header
+func VerifyDigest(header string) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.digestHeader = header
+    // Digests are computed over the identity encoding
+    cfg.header["Accept-Encoding"] = "identity"
+  }
+}
+
+// Sends the remaining context deadline as X-Request-Timeout milliseconds
+func PropagateDeadline() requestOption {
+  return func(cfg *requestConfig) {
+    cfg.propagateDeadline = true
+  }
+}
+
 func traceReq(method string, cfg *requestConfig) {
   // HTTP method and URL
   fmt.Printf("%s %s\n", method, cfg.url)
@@ -244,22 +332,15 @@ func traceReq(method string, cfg *requestConfig) {
   }
   // Body
   if len(cfg.reqBytes) > 0 {
-    if contType == appJSON {
-      fmt.Printf(">> %s\n", udump.JSON(cfg.reqBytes))
-    } else {
-      fmt.Printf(">> %s\n", cfg.reqBytes)
-    }
+    fmt.Printf(">> %s\n", udump.Pretty(contType, cfg.reqBytes))
   }
 }
 
 func traceRes(res *http.Response, body []byte, start time.Time) {
   elapsed := time.Since(start).Truncate(time.Millisecond)
   if len(body) > 0 {
-    if res.Header.Get(contentType) == appJSON {
-      fmt.Printf("<< %d %s %s\n", res.StatusCode, elapsed, udump.JSON(body))
-    } else {
-      fmt.Printf("<< %d %s %s\n", res.StatusCode, elapsed, body)
-    }
+    pretty := udump.Pretty(res.Header.Get(contentType), body)
+    fmt.Printf("<< %d %s %s\n", res.StatusCode, elapsed, pretty)
   } else {
     fmt.Printf("<< %d %s\n", res.StatusCode, elapsed)
   }
@@ -269,22 +350,28 @@ func (c *Client) request(
   ctx context.Context, method string, opts ...requestOption,
 ) (*http.Response, error) {
   // Process request configuration options
-  success := []int{200, 201, 202, 204}
   cfg := &requestConfig{
-    query: make(map[string]string),
-    header: make(map[string]string),
+    query: make(url.Values),
+    header: make(map[string]string, len(c.header)),
+    meta: make(map[string]string),
   }
+  maps.Copy(cfg.header, c.header)
   for _, opt := range opts {
     opt(cfg)
     if cfg.err != nil {
       return nil, cfg.err
     }
   }
+  success := []int{200, 201, 202, 204}
+  if len(cfg.success) > 0 {
+    success = cfg.success
+  }
   // URL
   if len(c.baseURL) == 0 && len(cfg.url) == 0 {
     return nil, fmt.Errorf("%s empty request URL", method)
   }
   url2 := c.baseURL + cfg.url
+  ctx = withMetas(ctx, cfg.meta)
   // Create a request
   req, err := http.NewRequestWithContext(
     ctx, method, url2, bytes.NewReader(cfg.reqBytes),
@@ -294,53 +381,119 @@ func (c *Client) request(
   }
   // Query
   query := req.URL.Query()
-  for key, value := range cfg.query {
-    query.Set(key, value)
+  for key, values := range cfg.query {
+    query[key] = values
   }
   req.URL.RawQuery = query.Encode()
   // Header
   for key, value := range cfg.header {
     req.Header.Set(key, value)
   }
+  if deadline, exist := ctx.Deadline(); exist && cfg.propagateDeadline {
+    remaining := max(time.Until(deadline).Milliseconds(), 1)
+    req.Header.Set("X-Request-Timeout", strconv.FormatInt(remaining, 10))
+  }
   var start time.Time
   if cfg.trace {
     traceReq(method, cfg)
     start = time.Now()
   }
   // Perform a request
-  res, err := c.client.Do(req)
+  res, err := c.do(req)
   if err != nil {
     return nil, err
   }
   defer func() {
     _ = res.Body.Close()
   }()
-  body, err := io.ReadAll(res.Body)
-  if err != nil {
-    return nil, err
+  reader := io.Reader(res.Body)
+  var digest hash.Hash
+  if len(cfg.digestHeader) > 0 {
+    digest, err = newDigest(cfg.digestHeader)
+    if err != nil {
+      return nil, err
+    }
+    reader = io.TeeReader(res.Body, digest)
+  }
+  streamed := cfg.resStream != nil || len(cfg.resFile) > 0
+  if streamed && slices.Contains(success, res.StatusCode) {
+    verify := func() error {
+      if digest == nil {
+        return nil
+      }
+      return verifyDigest(res, cfg.digestHeader, digest)
+    }
+    if len(cfg.resFile) > 0 {
+      err = streamToFile(cfg.resFile, reader, verify)
+    } else {
+      err = cfg.resStream(reader)
+      if err == nil {
+        err = verify()
+      }
+    }
+    if err != nil {
+      return res, err
+    }
+    if cfg.trace {
+      traceRes(res, nil, start)
+    }
+    return res, nil
+  }
+  var body []byte
+  if cfg.resSpool != nil {
+    // Large bodies are spooled to a temp file removed on close
+    spooled, head, err := spool(reader, cfg.spoolThreshold)
+    if err != nil {
+      return nil, err
+    }
+    *cfg.resSpool = spooled
+    body = head
+  } else {
+    body, err = io.ReadAll(reader)
+    if err != nil {
+      return nil, err
+    }
+  }
+  // Error responses are not expected to carry the digest header
+  if digest != nil && slices.Contains(success, res.StatusCode) {
+    err = verifyDigest(res, cfg.digestHeader, digest)
+    if err != nil {
+      if cfg.resSpool != nil {
+        _ = (*cfg.resSpool).Close()
+        *cfg.resSpool = nil
+      }
+      return nil, err
+    }
   }
   if cfg.trace {
     traceRes(res, body, start)
   }
+  // Response bytes are available alongside the decoded values
+  if cfg.resBytes != nil {
+    *cfg.resBytes = body
+  }
   // Valid response
   if slices.Contains(success, res.StatusCode) && cfg.resValue != nil {
     err = json.Unmarshal(body, cfg.resValue)
     if err != nil {
-      return nil, err
+      return res, newDecodeError(
+        res.StatusCode, res.Header.Get(contentType), body, err,
+      )
     }
     return res, nil
   }
   // Error response
+  if !slices.Contains(success, res.StatusCode) && cfg.errAs != nil {
+    return res, cfg.errAs(res, body)
+  }
   if !slices.Contains(success, res.StatusCode) && cfg.resError != nil {
     err = json.Unmarshal(body, cfg.resError)
     if err != nil {
-      return nil, err
+      return res, newDecodeError(
+        res.StatusCode, res.Header.Get(contentType), body, err,
+      )
     }
   }
-  // Response bytes
-  if cfg.resBytes != nil {
-    *cfg.resBytes = body
-  }
   return res, nil
 }
 
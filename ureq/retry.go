@@ -0,0 +1,223 @@
+package ureq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/urand"
+)
+
+// Decides whether and when to retry an attempt, attempt starts at 1
+type RetryProfile interface {
+  Prepare(req *http.Request)
+  Retry(
+    req *http.Request, attempt int, res *http.Response, err error,
+  ) (time.Duration, bool)
+}
+
+func retryAfter(res *http.Response) (time.Duration, bool) {
+  if res == nil {
+    return 0, false
+  }
+  value := res.Header.Get("Retry-After")
+  if len(value) == 0 {
+    return 0, false
+  }
+  secs, err := strconv.Atoi(value)
+  if err == nil {
+    return time.Duration(max(secs, 0)) * time.Second, true
+  }
+  date, err := http.ParseTime(value)
+  if err == nil {
+    return max(time.Until(date), 0), true
+  }
+  return 0, false
+}
+
+func backoff(attempt int, base, cap time.Duration) time.Duration {
+  d := min(base << min(attempt - 1, 16), cap)
+  // Full jitter
+  return time.Duration(urand.RandInt(0, int(d) + 1))
+}
+
+type stripeRetry struct {
+  maxRetries int
+}
+
+// Stripe documented retries: network errors, 409, 429 and 5xx honoring
+// Stripe-Should-Retry and Retry-After. POSTs get an Idempotency-Key so
+// retries are safe
+func StripeRetry(maxRetries int) RetryProfile {
+  return &stripeRetry{maxRetries: maxRetries}
+}
+
+func (s *stripeRetry) Prepare(req *http.Request) {
+  if req.Method == http.MethodPost &&
+    len(req.Header.Get("Idempotency-Key")) == 0 {
+    req.Header.Set("Idempotency-Key", urand.RandHex(32))
+  }
+}
+
+func (s *stripeRetry) Retry(
+  req *http.Request, attempt int, res *http.Response, err error,
+) (time.Duration, bool) {
+  if attempt > s.maxRetries {
+    return 0, false
+  }
+  delay := backoff(attempt, 500 * time.Millisecond, 8 * time.Second)
+  if err != nil {
+    return delay, !errors.Is(err, context.Canceled)
+  }
+  switch res.Header.Get("Stripe-Should-Retry") {
+  case "true":
+    return delay, true
+  case "false":
+    return 0, false
+  }
+  switch {
+  case res.StatusCode == http.StatusConflict,
+    res.StatusCode == http.StatusTooManyRequests,
+    res.StatusCode >= 500:
+    after, exist := retryAfter(res)
+    if exist && after <= time.Minute {
+      delay = max(delay, after)
+    }
+    return delay, true
+  }
+  return 0, false
+}
+
+type awsRetry struct {
+  maxAttempts int
+  mtx sync.Mutex
+  tokens int
+  capacity int
+}
+
+// AWS SDK standard mode: throttling and transient errors are retried with
+// capped exponential backoff, retries draw from a token bucket refilled by
+// successes so a failing dependency is not amplified
+func AWSRetry(maxAttempts int) RetryProfile {
+  return &awsRetry{maxAttempts: maxAttempts, tokens: 500, capacity: 500}
+}
+
+func (a *awsRetry) Prepare(req *http.Request) {}
+
+func (a *awsRetry) retryable(res *http.Response, err error) (bool, int) {
+  if err != nil {
+    if errors.Is(err, context.Canceled) {
+      return false, 0
+    }
+    // Timeouts cost more retry tokens
+    if errors.Is(err, context.DeadlineExceeded) {
+      return true, 10
+    }
+    return true, 5
+  }
+  switch res.StatusCode {
+  case http.StatusTooManyRequests, http.StatusInternalServerError,
+    http.StatusBadGateway, http.StatusServiceUnavailable,
+    http.StatusGatewayTimeout:
+    return true, 5
+  case http.StatusBadRequest:
+    // Throttling errors reported as 400 by some services
+    code := res.Header.Get("X-Amzn-ErrorType")
+    return len(code) > 0 && (code == "ThrottlingException" ||
+      code == "RequestLimitExceeded" || code == "TooManyRequestsException"), 5
+  }
+  return false, 0
+}
+
+func (a *awsRetry) Retry(
+  req *http.Request, attempt int, res *http.Response, err error,
+) (time.Duration, bool) {
+  retry, cost := a.retryable(res, err)
+  a.mtx.Lock()
+  defer a.mtx.Unlock()
+  if !retry {
+    if err == nil && res.StatusCode < 400 {
+      a.tokens = min(a.tokens + 1, a.capacity)
+    }
+    return 0, false
+  }
+  if attempt >= a.maxAttempts || a.tokens < cost {
+    return 0, false
+  }
+  a.tokens -= cost
+  return backoff(attempt, 100 * time.Millisecond, 20 * time.Second), true
+}
+
+type exponentialRetry struct {
+  maxRetries int
+  base time.Duration
+}
+
+// Generic retries of network errors, 429 and 502-504 honoring Retry-After.
+// POST and PATCH are retried only with an Idempotency-Key
+func ExponentialRetry(maxRetries int, base time.Duration) RetryProfile {
+  return &exponentialRetry{maxRetries: maxRetries, base: base}
+}
+
+func (e *exponentialRetry) Prepare(req *http.Request) {}
+
+func (e *exponentialRetry) Retry(
+  req *http.Request, attempt int, res *http.Response, err error,
+) (time.Duration, bool) {
+  if attempt > e.maxRetries {
+    return 0, false
+  }
+  if (req.Method == http.MethodPost || req.Method == http.MethodPatch) &&
+    len(req.Header.Get("Idempotency-Key")) == 0 {
+    return 0, false
+  }
+  delay := backoff(attempt, e.base, 30 * time.Second)
+  if err != nil {
+    return delay, !errors.Is(err, context.Canceled)
+  }
+  switch res.StatusCode {
+  case http.StatusTooManyRequests, http.StatusBadGateway,
+    http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+    after, exist := retryAfter(res)
+    if exist {
+      delay = max(delay, after)
+    }
+    return delay, true
+  }
+  return 0, false
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+  if c.retry == nil {
+    return c.roundTrip(req)
+  }
+  c.retry.Prepare(req)
+  for attempt := 1; ; attempt++ {
+    res, err := c.roundTrip(req)
+    delay, retry := c.retry.Retry(req, attempt, res, err)
+    if !retry || req.Body != nil && req.GetBody == nil {
+      return res, err
+    }
+    if res != nil {
+      _, _ = io.Copy(io.Discard, res.Body)
+      _ = res.Body.Close()
+    }
+    timer := time.NewTimer(delay)
+    select {
+    case <-timer.C:
+    case <-req.Context().Done():
+      timer.Stop()
+      return nil, req.Context().Err()
+    }
+    if req.GetBody != nil {
+      req.Body, err = req.GetBody()
+      if err != nil {
+        return nil, err
+      }
+    }
+  }
+}
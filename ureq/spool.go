@@ -0,0 +1,53 @@
+package ureq
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+type spoolFile struct {
+  *os.File
+}
+
+func (f *spoolFile) Close() error {
+  err := f.File.Close()
+  _ = os.Remove(f.Name())
+  return err
+}
+
+type memorySpool struct {
+  *bytes.Reader
+}
+
+func (m *memorySpool) Close() error {
+  return nil
+}
+
+// Returns the in-memory body when below the threshold, nil otherwise
+func spool(r io.Reader, threshold int64) (io.ReadSeekCloser, []byte, error) {
+  head, err := io.ReadAll(io.LimitReader(r, threshold + 1))
+  if err != nil {
+    return nil, nil, err
+  }
+  if int64(len(head)) <= threshold {
+    return &memorySpool{Reader: bytes.NewReader(head)}, head, nil
+  }
+  file, err := os.CreateTemp("", "ureq-spool-*")
+  if err != nil {
+    return nil, nil, err
+  }
+  spf := &spoolFile{File: file}
+  _, err = spf.Write(head)
+  if err == nil {
+    _, err = io.Copy(spf, r)
+  }
+  if err == nil {
+    _, err = spf.Seek(0, io.SeekStart)
+  }
+  if err != nil {
+    _ = spf.Close()
+    return nil, nil, err
+  }
+  return spf, nil, nil
+}
@@ -0,0 +1,49 @@
+package ureq
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Hands the live body of a successful response to fn, error responses are
+// read as usual. The client Timeout covers the body, raise it for large
+// downloads or rely on the context
+func ResStream(fn func(body io.Reader) error) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.resStream = fn
+  }
+}
+
+// Streams the body of a successful response into a temp file next to the
+// path, renamed into place once the body and its digest are complete
+func ResToFile(path string) requestOption {
+  return func(cfg *requestConfig) {
+    cfg.resFile = path
+  }
+}
+
+func streamToFile(path string, body io.Reader, verify func() error) error {
+  dir, base := filepath.Split(path)
+  file, err := os.CreateTemp(dir, base + ".*.part")
+  if err != nil {
+    return err
+  }
+  defer func() {
+    _ = os.Remove(file.Name())
+  }()
+  _, err = io.Copy(file, body)
+  if err != nil {
+    _ = file.Close()
+    return err
+  }
+  err = file.Close()
+  if err != nil {
+    return err
+  }
+  err = verify()
+  if err != nil {
+    return err
+  }
+  return os.Rename(file.Name(), path)
+}
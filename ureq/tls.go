@@ -0,0 +1,58 @@
+package ureq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+func (cfg *clientConfig) tlsConfig() *tls.Config {
+  if cfg.tls == nil {
+    cfg.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+  }
+  return cfg.tls
+}
+
+// Server certificates are verified against the pool instead of the system
+// roots e.g. userv.ReadCertPool("partner-ca.pem")
+func TLSRootCAs(pool *x509.CertPool) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.tlsConfig().RootCAs = pool
+  }
+}
+
+// Mutual TLS client certificate
+func TLSClientCert(cert tls.Certificate) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.tlsConfig().Certificates = []tls.Certificate{cert}
+  }
+}
+
+// Mutual TLS client certificate read on every handshake, so rotated files
+// are picked up without a restart
+func TLSClientCertFile(certFile, keyFile string) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.tlsConfig().GetClientCertificate = func(
+      info *tls.CertificateRequestInfo,
+    ) (*tls.Certificate, error) {
+      cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+      if err != nil {
+        return nil, err
+      }
+      return &cert, nil
+    }
+  }
+}
+
+// TLS 1.2 by default
+func TLSMinVersion(version uint16) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.tlsConfig().MinVersion = version
+  }
+}
+
+// Development only: server certificates are not verified
+func TLSInsecureSkipVerify() clientOption {
+  return func(cfg *clientConfig) {
+    cfg.tlsConfig().InsecureSkipVerify = true
+  }
+}
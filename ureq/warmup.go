@@ -0,0 +1,90 @@
+package ureq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Parallel Warmup connections per path kept idle, up to MaxIdleConnsPerHost
+func WarmupConns(conns int) clientOption {
+  return func(cfg *clientConfig) {
+    cfg.warmupConns = conns
+  }
+}
+
+// Warmup with GET instead of HEAD to prime server and proxy caches
+func WarmupGET() clientOption {
+  return func(cfg *clientConfig) {
+    cfg.warmupMethod = http.MethodGet
+  }
+}
+
+func (c *Client) warmup(ctx context.Context, method, url2 string) error {
+  req, err := http.NewRequestWithContext(ctx, method, url2, nil)
+  if err != nil {
+    return err
+  }
+  for key, value := range c.header {
+    req.Header.Set(key, value)
+  }
+  res, err := c.roundTrip(req)
+  if err != nil {
+    return err
+  }
+  // A drained body returns the connection to the idle pool
+  _, _ = io.Copy(io.Discard, res.Body)
+  return res.Body.Close()
+}
+
+// Resolves DNS and establishes TCP and TLS connections for the paths
+// relative to the base URL or absolute URLs, the base URL by default. Any
+// response status counts as warm
+func (c *Client) Warmup(ctx context.Context, paths ...string) error {
+  if len(paths) == 0 {
+    paths = []string{""}
+  }
+  urls := make([]string, 0, len(paths))
+  hosts := make(map[string]struct{})
+  for _, path := range paths {
+    url2 := path
+    if !strings.Contains(path, "://") {
+      url2 = c.baseURL + path
+    }
+    u, err := url.Parse(url2)
+    if err != nil || len(u.Host) == 0 {
+      return fmt.Errorf("warmup invalid URL %q", url2)
+    }
+    urls = append(urls, url2)
+    hosts[u.Hostname()] = struct{}{}
+  }
+  var mtx sync.Mutex
+  var errs []error
+  for host := range hosts {
+    _, err := net.DefaultResolver.LookupHost(ctx, host)
+    if err != nil {
+      errs = append(errs, err)
+    }
+  }
+  var wg sync.WaitGroup
+  for _, url2 := range urls {
+    for range max(c.warmupConns, 1) {
+      wg.Go(func() {
+        err := c.warmup(ctx, c.warmupMethod, url2)
+        if err != nil {
+          mtx.Lock()
+          errs = append(errs, fmt.Errorf("warmup: %w", err))
+          mtx.Unlock()
+        }
+      })
+    }
+  }
+  wg.Wait()
+  return errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+package userv
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+  return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func Principal(ctx context.Context) (string, bool) {
+  principal, exist := ctx.Value(principalKey{}).(string)
+  return principal, exist
+}
+
+type authConfig struct {
+  realm string
+  header string
+  success func(ctx context.Context, principal string) context.Context
+}
+
+type authOption func(cfg *authConfig)
+
+func AuthRealm(realm string) authOption {
+  return func(cfg *authConfig) {
+    cfg.realm = realm
+  }
+}
+
+func AuthHeader(header string) authOption {
+  return func(cfg *authConfig) {
+    cfg.header = header
+  }
+}
+
+func AuthSuccess(
+  success func(ctx context.Context, principal string) context.Context,
+) authOption {
+  return func(cfg *authConfig) {
+    cfg.success = success
+  }
+}
+
+func newAuthConfig(opts ...authOption) *authConfig {
+  cfg := &authConfig{
+    realm: "restricted",
+    header: "X-Api-Key",
+    success: WithPrincipal,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return cfg
+}
+
+func secretEqual(a, b string) bool {
+  ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+  return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+func BasicAuth(
+  users map[string]string, opts ...authOption, // user => password
+) Middleware {
+  cfg := newAuthConfig(opts...)
+  challenge := fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, cfg.realm)
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      user, pass, ok := r.BasicAuth()
+      valid := 0
+      for u, p := range users {
+        // Check all credentials to not leak timing
+        if secretEqual(user, u) && secretEqual(pass, p) {
+          valid = 1
+        }
+      }
+      if !ok || valid == 0 {
+        w.Header().Set("WWW-Authenticate", challenge)
+        WriteError(w, Unautorized("invalid credentials"))
+        return
+      }
+      next(w, r.WithContext(cfg.success(r.Context(), user)))
+    }
+  }
+}
+
+func APIKey(
+  keys map[string]string, opts ...authOption, // API key => principal
+) Middleware {
+  cfg := newAuthConfig(opts...)
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      key := r.Header.Get(cfg.header)
+      var principal string
+      for k, p := range keys {
+        // Check all keys to not leak timing
+        if secretEqual(key, k) {
+          principal = p
+        }
+      }
+      if len(key) == 0 || len(principal) == 0 {
+        WriteError(w, Unautorized("invalid API key"))
+        return
+      }
+      next(w, r.WithContext(cfg.success(r.Context(), principal)))
+    }
+  }
+}
@@ -0,0 +1,90 @@
+package userv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type QueryBudgetExceeded struct {
+  Statements int
+  MaxStatements int
+  Duration time.Duration
+  MaxDuration time.Duration
+}
+
+func (e *QueryBudgetExceeded) Error() string {
+  return fmt.Sprintf(
+    "query budget exceeded: %d/%d statements, %s/%s DB time",
+    e.Statements, e.MaxStatements, e.Duration, e.MaxDuration,
+  )
+}
+
+type queryBudget struct {
+  mtx sync.Mutex
+  maxStatements int
+  maxDuration time.Duration
+  statements int
+  duration time.Duration
+}
+
+type queryBudgetKey struct{}
+
+func WithQueryBudget(
+  ctx context.Context, maxStatements int, maxDuration time.Duration,
+) context.Context {
+  b := &queryBudget{maxStatements: maxStatements, maxDuration: maxDuration}
+  return context.WithValue(ctx, queryBudgetKey{}, b)
+}
+
+func QueryBudgetUsage(ctx context.Context) (int, time.Duration) {
+  b, exist := ctx.Value(queryBudgetKey{}).(*queryBudget)
+  if !exist {
+    return 0, 0
+  }
+  b.mtx.Lock()
+  defer b.mtx.Unlock()
+  return b.statements, b.duration
+}
+
+// Per-request statement and DB time budget consumed by the uquery helpers
+// e.g. to surface N+1 queries in staging
+func QueryBudget(
+  maxStatements int, maxDuration time.Duration,
+) func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      ctx := WithQueryBudget(r.Context(), maxStatements, maxDuration)
+      next.ServeHTTP(w, r.WithContext(ctx))
+    })
+  }
+}
+
+// Fails with *QueryBudgetExceeded before running the statement when the
+// budget is spent, the DB time is recorded after the statement and never
+// turns its result into an error
+func TrackQuery(ctx context.Context, query func() error) error {
+  b, exist := ctx.Value(queryBudgetKey{}).(*queryBudget)
+  if !exist {
+    return query()
+  }
+  b.mtx.Lock()
+  if b.statements >= b.maxStatements || b.duration > b.maxDuration {
+    err := &QueryBudgetExceeded{
+      Statements: b.statements + 1, MaxStatements: b.maxStatements,
+      Duration: b.duration, MaxDuration: b.maxDuration,
+    }
+    b.mtx.Unlock()
+    return err
+  }
+  b.statements++
+  b.mtx.Unlock()
+  start := time.Now()
+  err := query()
+  b.mtx.Lock()
+  defer b.mtx.Unlock()
+  b.duration += time.Since(start)
+  return err
+}
@@ -0,0 +1,125 @@
+package userv
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/volodymyrprokopyuk/go-util/ucache"
+)
+
+type cachedResponse struct {
+  header http.Header
+  body []byte
+}
+
+type CachedHandler struct {
+  cache *ucache.Cache[string, cachedResponse]
+  ttl time.Duration
+  keyFn func(r *http.Request) string
+  handler http.HandlerFunc
+}
+
+// Method, URI, Accept and principal when present
+func CacheKey(r *http.Request) string {
+  key := r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("Accept")
+  principal, exist := Principal(r.Context())
+  if exist {
+    key += " " + principal
+  }
+  return key
+}
+
+// Responses with an explicit content type other than event streams, not
+// marked no-store or private
+func cacheable(header http.Header) bool {
+  contentType := header.Get("Content-Type")
+  cacheControl := header.Get("Cache-Control")
+  return len(contentType) > 0 &&
+    !strings.HasPrefix(contentType, "text/event-stream") &&
+    !strings.Contains(cacheControl, "no-store") &&
+    !strings.Contains(cacheControl, "private")
+}
+
+// Caches cacheable 200 responses to GET requests keyed by keyFn, CacheKey when nil
+func Cached(
+  ttl time.Duration, keyFn func(r *http.Request) string,
+  handler http.HandlerFunc,
+) *CachedHandler {
+  if keyFn == nil {
+    keyFn = CacheKey
+  }
+  return &CachedHandler{
+    cache: ucache.New[string, cachedResponse](1024),
+    ttl: ttl,
+    keyFn: keyFn,
+    handler: handler,
+  }
+}
+
+func (c *CachedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodGet {
+    c.handler(w, r)
+    return
+  }
+  key := c.keyFn(r)
+  cached, exist := c.cache.Get(key)
+  if exist {
+    for name, values := range cached.header {
+      w.Header()[name] = values
+    }
+    w.Header().Set("X-Cache", "HIT")
+    w.WriteHeader(http.StatusOK)
+    _, _ = w.Write(cached.body)
+    return
+  }
+  w.Header().Set("X-Cache", "MISS")
+  rw := newRecordWriter(w)
+  c.handler(rw, r)
+  if rw.statusCode == http.StatusOK && cacheable(rw.header) {
+    header := rw.header.Clone()
+    header.Del("Set-Cookie")
+    cached = cachedResponse{header: header, body: rw.body.Bytes()}
+    c.cache.Set(key, cached, c.ttl)
+  }
+}
+
+func (c *CachedHandler) Invalidate(keys ...string) {
+  for _, key := range keys {
+    c.cache.Delete(key)
+  }
+}
+
+// e.g. InvalidatePrefix("GET /products") for all queries and accepts
+func (c *CachedHandler) InvalidatePrefix(prefix string) int {
+  return c.cache.DeleteFunc(func(key string) bool {
+    return strings.HasPrefix(key, prefix)
+  })
+}
+
+func (c *CachedHandler) Clear() {
+  c.cache.Clear()
+}
+
+// Invalidates the keys of successful responses on related routes e.g. POST,
+// the whole cache when no key functions are provided
+func (c *CachedHandler) Invalidates(
+  keyFns ...func(r *http.Request) string,
+) Middleware {
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      rw := &logWriter{ResponseWriter: w}
+      next(rw, r)
+      if rw.statusCode < 200 || rw.statusCode >= 300 {
+        return
+      }
+      if len(keyFns) == 0 {
+        c.Clear()
+        return
+      }
+      for _, keyFn := range keyFns {
+        c.Invalidate(keyFn(r))
+      }
+    }
+  }
+}
@@ -0,0 +1,136 @@
+package userv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type csrfConfig struct {
+  cookie string
+  header string
+  field string
+  sameSite http.SameSite
+  httpOnly bool
+  maxAge time.Duration
+}
+
+type csrfOption func(cfg *csrfConfig)
+
+func CSRFCookie(name string) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.cookie = name
+  }
+}
+
+func CSRFHeader(name string) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.header = name
+  }
+}
+
+func CSRFFormField(name string) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.field = name
+  }
+}
+
+func CSRFSameSite(sameSite http.SameSite) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.sameSite = sameSite
+  }
+}
+
+// The token is then available to clients only through CSRFToken
+func CSRFHttpOnly(httpOnly bool) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.httpOnly = httpOnly
+  }
+}
+
+func CSRFMaxAge(maxAge time.Duration) csrfOption {
+  return func(cfg *csrfConfig) {
+    cfg.maxAge = maxAge
+  }
+}
+
+type csrfKey struct{}
+
+func CSRFToken(ctx context.Context) string {
+  tok, _ := ctx.Value(csrfKey{}).(string)
+  return tok
+}
+
+type csrfTokenRes struct {
+  CSRFToken string `json:"csrfToken"`
+}
+
+func CSRFTokenHandler(w http.ResponseWriter, r *http.Request) {
+  w.Header().Set("Cache-Control", "no-store")
+  WriteResponse(w, http.StatusOK, csrfTokenRes{CSRFToken: CSRFToken(r.Context())})
+}
+
+func csrfSafeMethod(method string) bool {
+  switch method {
+  case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+    return true
+  default:
+    return false
+  }
+}
+
+// Double-submit cookie: unsafe methods must echo the cookie token in the
+// header or form field
+func CSRF(opts ...csrfOption) func(next http.Handler) http.Handler {
+  cfg := &csrfConfig{
+    cookie: "csrf_token",
+    header: "X-CSRF-Token",
+    field: "csrf_token",
+    sameSite: http.SameSiteStrictMode,
+    maxAge: 12 * time.Hour,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      var tok string
+      cookie, err := r.Cookie(cfg.cookie)
+      if err == nil && len(cookie.Value) >= 43 {
+        tok = cookie.Value
+      }
+      if !csrfSafeMethod(r.Method) {
+        sent := r.Header.Get(cfg.header)
+        ctype := r.Header.Get("Content-Type")
+        if len(sent) == 0 && (strings.HasPrefix(ctype, "multipart/form-data") ||
+          strings.HasPrefix(ctype, "application/x-www-form-urlencoded")) {
+          sent = r.PostFormValue(cfg.field)
+        }
+        if len(tok) == 0 || !secretEqual(sent, tok) {
+          WriteError(w, Forbidden("invalid CSRF token"))
+          return
+        }
+      }
+      if len(tok) == 0 {
+        rnd := make([]byte, 32)
+        _, _ = rand.Read(rnd)
+        tok = base64.RawURLEncoding.EncodeToString(rnd)
+        http.SetCookie(w, &http.Cookie{
+          Name: cfg.cookie,
+          Value: tok,
+          Path: "/",
+          HttpOnly: cfg.httpOnly,
+          Secure: true,
+          SameSite: cfg.sameSite,
+          MaxAge: int(cfg.maxAge.Seconds()),
+        })
+      }
+      w.Header().Add("Vary", "Cookie")
+      ctx := context.WithValue(r.Context(), csrfKey{}, tok)
+      next.ServeHTTP(w, r.WithContext(ctx))
+    })
+  }
+}
@@ -0,0 +1,73 @@
+package userv
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+  RequestTimeoutHeader = "X-Request-Timeout"
+  RequestBudgetHeader = "X-Request-Budget"
+)
+
+// Milliseconds or a Go duration e.g. 1500 or 1.5s
+func parseRequestTimeout(value string) (time.Duration, bool) {
+  ms, err := strconv.ParseInt(value, 10, 64)
+  if err == nil {
+    return time.Duration(ms) * time.Millisecond, ms > 0
+  }
+  d, err := time.ParseDuration(value)
+  return d, err == nil && d > 0
+}
+
+type deadlineWriter struct {
+  http.ResponseWriter
+  deadline time.Time
+  wroteHeader bool
+}
+
+func (d *deadlineWriter) Unwrap() http.ResponseWriter {
+  return d.ResponseWriter
+}
+
+func (d *deadlineWriter) WriteHeader(statusCode int) {
+  if !d.wroteHeader {
+    d.wroteHeader = true
+    remaining := max(time.Until(d.deadline).Milliseconds(), 0)
+    d.Header().Set(RequestBudgetHeader, strconv.FormatInt(remaining, 10))
+  }
+  d.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (d *deadlineWriter) Write(body []byte) (int, error) {
+  if !d.wroteHeader {
+    d.WriteHeader(http.StatusOK)
+  }
+  return d.ResponseWriter.Write(body)
+}
+
+// Sets the context deadline from the client X-Request-Timeout bounded by
+// maxTimeout, reports the remaining milliseconds in X-Request-Budget
+func Deadline(maxTimeout time.Duration) func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      timeout := maxTimeout
+      value := r.Header.Get(RequestTimeoutHeader)
+      if len(value) > 0 {
+        d, valid := parseRequestTimeout(value)
+        if !valid {
+          WriteError(w, BadRequest("invalid " + RequestTimeoutHeader))
+          return
+        }
+        timeout = min(d, maxTimeout)
+      }
+      ctx, cancel := context.WithTimeout(r.Context(), timeout)
+      defer cancel()
+      deadline, _ := ctx.Deadline()
+      dw := &deadlineWriter{ResponseWriter: w, deadline: deadline}
+      next.ServeHTTP(dw, r.WithContext(ctx))
+    })
+  }
+}
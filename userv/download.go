@@ -0,0 +1,65 @@
+package userv
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+type downloadConfig struct {
+  disposition string
+  contentType string
+  etag string
+}
+
+type downloadOption func(cfg *downloadConfig)
+
+func DownloadInline() downloadOption {
+  return func(cfg *downloadConfig) {
+    cfg.disposition = "inline"
+  }
+}
+
+func DownloadContentType(contentType string) downloadOption {
+  return func(cfg *downloadConfig) {
+    cfg.contentType = contentType
+  }
+}
+
+// Enables If-Range and If-None-Match validation
+func DownloadETag(etag string) downloadOption {
+  return func(cfg *downloadConfig) {
+    cfg.etag = etag
+  }
+}
+
+// Streams Range and If-Range requests from any io.ReaderAt (os.File, an S3
+// ranged GET adapter) without buffering the whole file
+func ServeContentFrom(
+  w http.ResponseWriter, r *http.Request, name string, modtime time.Time,
+  reader io.ReaderAt, size int64, opts ...downloadOption,
+) {
+  cfg := &downloadConfig{disposition: "attachment"}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  filename := path.Base(name)
+  disposition := mime.FormatMediaType(
+    cfg.disposition, map[string]string{"filename": filename},
+  )
+  if len(disposition) > 0 {
+    w.Header().Set("Content-Disposition", disposition)
+  }
+  if len(cfg.contentType) == 0 {
+    cfg.contentType = mime.TypeByExtension(path.Ext(filename))
+  }
+  if len(cfg.contentType) > 0 {
+    w.Header().Set("Content-Type", cfg.contentType)
+  }
+  if len(cfg.etag) > 0 {
+    w.Header().Set("ETag", cfg.etag)
+  }
+  http.ServeContent(w, r, filename, modtime, io.NewSectionReader(reader, 0, size))
+}
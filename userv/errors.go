@@ -0,0 +1,108 @@
+package userv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type wrapError struct {
+  public error
+  cause error
+}
+
+// Full message with the cause for logs
+func (e *wrapError) Error() string {
+  if e.cause == nil {
+    return e.public.Error()
+  }
+  return fmt.Sprintf("%s: %s", e.public, e.cause)
+}
+
+func (e *wrapError) Unwrap() []error {
+  if e.cause == nil {
+    return []error{e.public}
+  }
+  return []error{e.public, e.cause}
+}
+
+// The public error (BadRequest, NotFound...) selects the status code and is
+// the only message WriteError exposes, the cause is kept for logs and errors.Is/As
+func Wrap(public, cause error) error {
+  return &wrapError{public: public, cause: cause}
+}
+
+func Cause(err error) error {
+  var werr *wrapError
+  if errors.As(err, &werr) {
+    return werr.cause
+  }
+  return nil
+}
+
+var (
+  errorMappersMtx sync.RWMutex
+  errorMappers []func(err error) error
+)
+
+// Registers a translation of domain errors into HTTP errors consulted by
+// WriteError in order, a mapper returns nil for errors it does not handle.
+// Wrap the domain error to keep it as the cause
+func MapError(mapper func(err error) error) {
+  errorMappersMtx.Lock()
+  defer errorMappersMtx.Unlock()
+  errorMappers = append(errorMappers, mapper)
+}
+
+func mapError(err error) error {
+  errorMappersMtx.RLock()
+  defer errorMappersMtx.RUnlock()
+  for _, mapper := range errorMappers {
+    mapped := mapper(err)
+    if mapped != nil {
+      return mapped
+    }
+  }
+  return err
+}
+
+// The innermost public error of Wrap, the cause never selects the status code
+func publicError(err error) error {
+  var werr *wrapError
+  for errors.As(err, &werr) {
+    err = werr.public
+  }
+  return err
+}
+
+func publicMessage(err error) string {
+  return publicError(err).Error()
+}
+
+func BadRequestf(cause error, format string, args ...any) error {
+  return Wrap(BadRequest(fmt.Sprintf(format, args...)), cause)
+}
+
+func Unautorizedf(cause error, format string, args ...any) error {
+  return Wrap(Unautorized(fmt.Sprintf(format, args...)), cause)
+}
+
+func Forbiddenf(cause error, format string, args ...any) error {
+  return Wrap(Forbidden(fmt.Sprintf(format, args...)), cause)
+}
+
+func NotFoundf(cause error, format string, args ...any) error {
+  return Wrap(NotFound(fmt.Sprintf(format, args...)), cause)
+}
+
+func InternalServerErrorf(cause error, format string, args ...any) error {
+  return Wrap(InternalServerError(fmt.Sprintf(format, args...)), cause)
+}
+
+func BadGatewayf(cause error, format string, args ...any) error {
+  return Wrap(BadGateway(fmt.Sprintf(format, args...)), cause)
+}
+
+func ServiceUnavailablef(cause error, format string, args ...any) error {
+  return Wrap(ServiceUnavailable(fmt.Sprintf(format, args...)), cause)
+}
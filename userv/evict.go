@@ -0,0 +1,33 @@
+package userv
+
+import (
+	"time"
+)
+
+// Writes between scans of an in-memory store for expired entries
+const evictionInterval = 1024
+
+// Amortizes the eviction of expired entries of in-memory stores over writes
+// instead of scanning the whole map on every write. Readers still treat
+// expired entries as absent
+type Evictor struct {
+  writes int
+}
+
+// Deletes the expired entries of m every evictionInterval writes, the caller
+// holds the store lock
+func EvictExpired[K comparable, V any](
+  ev *Evictor, m map[K]V, expiry func(entry V) time.Time,
+) {
+  ev.writes++
+  if ev.writes < evictionInterval {
+    return
+  }
+  ev.writes = 0
+  now := time.Now()
+  for key, entry := range m {
+    if now.After(expiry(entry)) {
+      delete(m, key)
+    }
+  }
+}
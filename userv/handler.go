@@ -3,12 +3,16 @@ package userv
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -39,6 +43,30 @@ func (e NotFound) Error() string {
   return string(e)
 }
 
+type MethodNotAllowed string // 405
+
+func (e MethodNotAllowed) Error() string {
+  return string(e)
+}
+
+type NotAcceptable string // 406
+
+func (e NotAcceptable) Error() string {
+  return string(e)
+}
+
+type Conflict string // 409
+
+func (e Conflict) Error() string {
+  return string(e)
+}
+
+type TooManyRequests string // 429
+
+func (e TooManyRequests) Error() string {
+  return string(e)
+}
+
 type InternalServerError string // 500
 
 func (e InternalServerError) Error() string {
@@ -68,9 +96,14 @@ func errorStatusCode(err error) int {
   var unauthorized Unautorized
   var forbidden Forbidden
   var notFound NotFound
+  var methodNotAllowed MethodNotAllowed
+  var notAcceptable NotAcceptable
+  var conflict Conflict
+  var tooManyRequests TooManyRequests
   var notImplemented NotImplemented
   var badGateway BadGateway
   var serviceUnavailable ServiceUnavailable
+  err = publicError(err)
   switch {
   case errors.As(err, &badRequest):
     return http.StatusBadRequest
@@ -80,6 +113,14 @@ func errorStatusCode(err error) int {
     return http.StatusForbidden
   case errors.As(err, &notFound):
     return http.StatusNotFound
+  case errors.As(err, &methodNotAllowed):
+    return http.StatusMethodNotAllowed
+  case errors.As(err, &notAcceptable):
+    return http.StatusNotAcceptable
+  case errors.As(err, &conflict):
+    return http.StatusConflict
+  case errors.As(err, &tooManyRequests):
+    return http.StatusTooManyRequests
   case errors.As(err, &notImplemented):
     return http.StatusNotImplemented
   case errors.As(err, &badGateway):
@@ -109,24 +150,74 @@ func ReadBody[T any](r *http.Request) (*T, error) {
 }
 
 type resError struct {
-  Error string `json:"error"`
+  XMLName xml.Name `json:"-" xml:"response"`
+  Error string `json:"error" xml:"error"`
 }
 
-func WriteResponse(w http.ResponseWriter, statusCode int, res any) {
-  w.Header().Set("Content-Type", "application/json")
+// Headers are set before the status is written, 204 and 304 have no body
+func WriteResponse(
+  w http.ResponseWriter, statusCode int, res any, opts ...responseOption,
+) {
+  cfg := &responseConfig{header: make(http.Header)}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  for key, values := range cfg.header {
+    w.Header()[key] = values
+  }
+  if !bodyAllowed(statusCode) {
+    w.WriteHeader(statusCode)
+    return
+  }
+  contentType, encode := responseEncoder(w)
+  var eres []byte
+  var err error
+  switch r := res.(type) {
+  case nil:
+    if len(cfg.contentType) > 0 {
+      contentType = cfg.contentType
+    }
+  case []byte:
+    if len(cfg.contentType) > 0 {
+      contentType, eres = cfg.contentType, r
+      break
+    }
+    contentType, eres, err = encodeResponse(contentType, encode, r)
+  case string:
+    if len(cfg.contentType) > 0 {
+      contentType, eres = cfg.contentType, []byte(r)
+      break
+    }
+    contentType, eres, err = encodeResponse(contentType, encode, r)
+  default:
+    if len(cfg.contentType) > 0 {
+      contentType, encode, err = contentTypeEncoder(cfg.contentType)
+      if err == nil {
+        eres, err = encode(res)
+      }
+      break
+    }
+    contentType, eres, err = encodeResponse(contentType, encode, res)
+  }
+  if err != nil {
+    WriteError(w, InternalServerError(err.Error()))
+    return
+  }
+  w.Header().Set("Content-Type", contentType)
   w.WriteHeader(statusCode)
-  if res != nil {
-    jres, _ := json.Marshal(res)
-    _, _ = w.Write(jres)
+  if len(eres) > 0 {
+    _, _ = w.Write(eres)
   }
 }
 
 func WriteError(w http.ResponseWriter, err error) {
-  w.Header().Set("Content-Type", "application/json")
+  err = mapError(err)
+  contentType, encode := responseEncoder(w)
+  res := resError{Error: publicMessage(err)}
+  contentType, eres, _ := encodeResponse(contentType, encode, res)
+  w.Header().Set("Content-Type", contentType)
   w.WriteHeader(errorStatusCode(err))
-  res := resError{Error: err.Error()}
-  jres, _ := json.Marshal(res)
-  _, _ = w.Write(jres)
+  _, _ = w.Write(eres)
 }
 
 type Middleware func(next http.HandlerFunc) http.HandlerFunc
@@ -144,10 +235,57 @@ func NotFoundHandler(mux *http.ServeMux) func(next http.Handler) http.Handler {
   }
 }
 
+var routeMethods = []string{
+  http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+  http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+  var allowed []string
+  probe := *r
+  for _, method := range routeMethods {
+    probe.Method = method
+    _, pattern := mux.Handler(&probe)
+    if len(pattern) > 0 {
+      allowed = append(allowed, method)
+    }
+  }
+  return allowed
+}
+
+// Unknown paths get 404, known paths with a wrong method get 405 with the
+// Allow header, OPTIONS without an explicit handler gets the Allow header
+func RouteGuard(mux *http.ServeMux) func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      _, pattern := mux.Handler(r)
+      if len(pattern) > 0 {
+        next.ServeHTTP(w, r)
+        return
+      }
+      allowed := allowedMethods(mux, r)
+      if len(allowed) == 0 {
+        WriteError(w, NotFound("not found"))
+        return
+      }
+      if !slices.Contains(allowed, http.MethodOptions) {
+        allowed = append(allowed, http.MethodOptions)
+      }
+      w.Header().Set("Allow", strings.Join(allowed, ", "))
+      if r.Method == http.MethodOptions {
+        w.WriteHeader(http.StatusNoContent)
+        return
+      }
+      WriteError(w, MethodNotAllowed("method not allowed"))
+    })
+  }
+}
+
 type traceWriter struct {
   http.ResponseWriter
   statusCode int
-  body []byte
+  size int
+  body *capBuffer
 }
 
 func (t *traceWriter) WriteHeader(statusCode int) {
@@ -155,12 +293,40 @@ func (t *traceWriter) WriteHeader(statusCode int) {
   t.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (t *traceWriter) Unwrap() http.ResponseWriter {
+  return t.ResponseWriter
+}
+
 func (t *traceWriter) Write(body []byte) (int, error) {
-  t.body = body
-  return t.ResponseWriter.Write(body)
+  if t.statusCode == 0 {
+    t.statusCode = http.StatusOK
+  }
+  n, err := t.ResponseWriter.Write(body)
+  t.size += n
+  _, _ = t.body.Write(body[:n])
+  return n, err
 }
 
-func Trace(reTrace *regexp.Regexp) func(next http.Handler) http.Handler {
+type traceConfig struct {
+  bodyLimit int
+}
+
+type traceOption func(cfg *traceConfig)
+
+// Response bytes kept for tracing, the total size is always reported
+func TraceBodyLimit(limit int) traceOption {
+  return func(cfg *traceConfig) {
+    cfg.bodyLimit = limit
+  }
+}
+
+func Trace(
+  reTrace *regexp.Regexp, opts ...traceOption,
+) func(next http.Handler) http.Handler {
+  cfg := &traceConfig{bodyLimit: 64 << 10}
+  for _, opt := range opts {
+    opt(cfg)
+  }
   return func(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
       methodPath := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
@@ -169,17 +335,30 @@ func Trace(reTrace *regexp.Regexp) func(next http.Handler) http.Handler {
         body, _ := io.ReadAll(r.Body)
         r.Body = io.NopCloser(bytes.NewReader(body))
         if len(body) > 0 {
-          fmt.Printf("%s %s\n>> %s\n", r.Method, r.URL.Path, udump.JSON(body))
+          fmt.Printf(
+            "%s %s\n>> %s\n", r.Method, r.URL.Path,
+            udump.Pretty(r.Header.Get("Content-Type"), body),
+          )
         } else {
           fmt.Printf("%s %s\n", r.Method, r.URL.Path)
         }
-        tw := &traceWriter{ResponseWriter: w}
+        tw := &traceWriter{
+          ResponseWriter: w, body: &capBuffer{limit: cfg.bodyLimit},
+        }
         next.ServeHTTP(tw, r)
         elapsed := time.Since(start).Truncate(time.Millisecond)
-        if len(tw.body) > 0 {
-          fmt.Printf("<< %d %s %s\n", tw.statusCode, elapsed, udump.JSON(tw.body))
-        } else {
-          fmt.Printf("<< %d %s\n", tw.statusCode, elapsed)
+        switch {
+        case tw.body.truncated:
+          fmt.Printf(
+            "<< %d %s %dB %s...\n", tw.statusCode, elapsed, tw.size, tw.body.buf,
+          )
+        case len(tw.body.buf) > 0:
+          fmt.Printf(
+            "<< %d %s %dB %s\n", tw.statusCode, elapsed, tw.size,
+            udump.Pretty(tw.Header().Get("Content-Type"), tw.body.buf),
+          )
+        default:
+          fmt.Printf("<< %d %s %dB\n", tw.statusCode, elapsed, tw.size)
         }
         return
       }
@@ -191,6 +370,8 @@ func Trace(reTrace *regexp.Regexp) func(next http.Handler) http.Handler {
 type logWriter struct {
   http.ResponseWriter
   statusCode int
+  size int
+  body *capBuffer
 }
 
 func (l *logWriter) WriteHeader(statusCode int) {
@@ -198,42 +379,126 @@ func (l *logWriter) WriteHeader(statusCode int) {
   l.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (l *logWriter) Unwrap() http.ResponseWriter {
+  return l.ResponseWriter
+}
+
 func (l *logWriter) Write(body []byte) (int, error) {
-  return l.ResponseWriter.Write(body)
+  if l.statusCode == 0 {
+    l.statusCode = http.StatusOK
+  }
+  n, err := l.ResponseWriter.Write(body)
+  l.size += n
+  if l.body != nil {
+    _, _ = l.body.Write(body[:n])
+  }
+  return n, err
 }
 
 type httpLogEntry struct {
   Method string `json:"method"`
   Path string `json:"path"`
+  Route string `json:"route,omitempty"`
   Query string `json:"query,omitempty"`
   StatusCode int `json:"statusCode"`
+  Size int `json:"size"`
   Duration int `json:"duration"`
   RemoteIP string `json:"remoteIP"`
   UserAgent string `json:"userAgent"`
+  Fields map[string]any `json:"fields,omitempty"`
+  Timings map[string]int `json:"timings,omitempty"`
+  ReqBody any `json:"reqBody,omitempty"`
+  ResBody any `json:"resBody,omitempty"`
   Timestamp time.Time `json:"timestamp"`
 }
 
-var reRemoteIP = regexp.MustCompile(`^(\d{1,3}(?:\.\d{1,3}){3}):\d{1,5}`)
-var reRemoteIPv6 = regexp.MustCompile(`^\[([a-f\d:.]+)\]`)
-
 func RemoteIP(r *http.Request) string {
-  ip := r.RemoteAddr
-  header := r.Header.Get("X-Forwarded-For")
-  if len(header) > 0 {
-    ip = strings.Split(header, ",")[0]
+  addr, valid := clientAddr(r, TrustedProxies())
+  if !valid {
+    return r.RemoteAddr
   }
-  match := reRemoteIP.FindStringSubmatch(ip)
-  if len(match) > 1 {
-    ip = match[1]
+  return addr.String()
+}
+
+type logConfig struct {
+  writer io.Writer
+  sample float64
+  fields map[string]any
+  reqFields func(r *http.Request) map[string]any
+  bodySample float64
+  bodyErrors bool
+  bodyLimit int
+  redact map[string]bool
+}
+
+type logOption func(cfg *logConfig)
+
+// Combine with NewAsyncWriter, NewRotatingFile and NewSyslogSink
+func LogWriter(ws ...io.Writer) logOption {
+  return func(cfg *logConfig) {
+    if len(ws) == 1 {
+      cfg.writer = ws[0]
+      return
+    }
+    cfg.writer = multiSink(ws)
   }
-  match = reRemoteIPv6.FindStringSubmatch(ip)
-  if len(match) > 1 {
-    ip = match[1]
+}
+
+func LogSample(rate float64) logOption { // 2xx responses only
+  return func(cfg *logConfig) {
+    cfg.sample = min(max(rate, 0), 1)
   }
-  return ip
 }
 
-func Log(exclude []*regexp.Regexp) func (next http.Handler) http.Handler {
+func LogField(key string, value any) logOption {
+  return func(cfg *logConfig) {
+    cfg.fields[key] = value
+  }
+}
+
+func LogRequestFields(
+  fn func(r *http.Request) map[string]any,
+) logOption {
+  return func(cfg *logConfig) {
+    cfg.reqFields = fn
+  }
+}
+
+func LogBodies(sample float64, limit int) logOption {
+  return func(cfg *logConfig) {
+    cfg.bodySample = min(max(sample, 0), 1)
+    cfg.bodyLimit = limit
+  }
+}
+
+func LogBodiesOnError(limit int) logOption { // 4xx and 5xx
+  return func(cfg *logConfig) {
+    cfg.bodyErrors = true
+    cfg.bodyLimit = limit
+  }
+}
+
+func LogRedact(keys ...string) logOption {
+  return func(cfg *logConfig) {
+    for _, key := range keys {
+      cfg.redact[key] = true
+    }
+  }
+}
+
+func Log(
+  exclude []*regexp.Regexp, opts ...logOption,
+) func (next http.Handler) http.Handler {
+  cfg := &logConfig{
+    writer: os.Stdout,
+    sample: 1,
+    fields: make(map[string]any),
+    redact: make(map[string]bool),
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  capture := cfg.bodyLimit > 0 && (cfg.bodySample > 0 || cfg.bodyErrors)
   return func (next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
       methodPath := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
@@ -244,24 +509,53 @@ func Log(exclude []*regexp.Regexp) func (next http.Handler) http.Handler {
         }
       }
       start := time.Now()
+      r, tms := withTimings(r)
       lw := &logWriter{ResponseWriter: w}
+      var reqBody *capBuffer
+      if capture {
+        reqBody = &capBuffer{limit: cfg.bodyLimit}
+        lw.body = &capBuffer{limit: cfg.bodyLimit}
+        r.Body = struct{
+          io.Reader
+          io.Closer
+        }{io.TeeReader(r.Body, reqBody), r.Body}
+      }
       next.ServeHTTP(lw, r)
+      if lw.statusCode < 300 && cfg.sample < 1 &&
+        rand.Float64() >= cfg.sample {
+        return
+      }
       log := httpLogEntry{
         Method: r.Method,
         Path: r.URL.Path,
+        Route: r.Pattern,
         Query: r.URL.RawQuery,
         StatusCode: lw.statusCode,
+        Size: lw.size,
         Duration: int(time.Since(start).Milliseconds()),
         RemoteIP: RemoteIP(r),
         UserAgent: r.UserAgent(),
+        Timings: tms.millis(),
         Timestamp: time.Now().UTC().Truncate(time.Microsecond),
       }
+      if len(cfg.fields) > 0 || cfg.reqFields != nil {
+        log.Fields = make(map[string]any, len(cfg.fields))
+        maps.Copy(log.Fields, cfg.fields)
+        if cfg.reqFields != nil {
+          maps.Copy(log.Fields, cfg.reqFields(r))
+        }
+      }
+      if capture && (cfg.bodyErrors && lw.statusCode >= 400 ||
+        rand.Float64() < cfg.bodySample) {
+        log.ReqBody = logBody(reqBody, cfg.redact)
+        log.ResBody = logBody(lw.body, cfg.redact)
+      }
       jlog, err := json.Marshal(log)
       if err != nil {
         fmt.Fprintf(os.Stderr, "%s\n", err)
         return
       }
-      fmt.Printf("%s\n", jlog)
+      _, _ = fmt.Fprintf(cfg.writer, "%s\n", jlog)
     })
   }
 }
@@ -0,0 +1,74 @@
+package userv
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+type HeaderRule struct {
+  name string
+  optional bool
+  check func(value string) bool
+  format string
+}
+
+func HeaderPresent(name string) HeaderRule {
+  return HeaderRule{name: name}
+}
+
+func HeaderRegexp(name string, re *regexp.Regexp) HeaderRule {
+  return HeaderRule{name: name, check: re.MatchString, format: re.String()}
+}
+
+// e.g. HeaderCheck("X-Forwarded-Host", ucheck.CheckHostPort, "host:port")
+func HeaderCheck(
+  name string, check func(value string) bool, format string,
+) HeaderRule {
+  return HeaderRule{name: name, check: check, format: format}
+}
+
+// Validates the format only when the header is present
+func (h HeaderRule) Optional() HeaderRule {
+  h.optional = true
+  return h
+}
+
+type headerViolation struct {
+  Header string `json:"header"`
+  Reason string `json:"reason"`
+}
+
+type resHeaderError struct {
+  Error string `json:"error"`
+  Violations []headerViolation `json:"violations"`
+}
+
+func RequireHeaders(rules ...HeaderRule) Middleware {
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      var violations []headerViolation
+      for _, rule := range rules {
+        value := r.Header.Get(rule.name)
+        switch {
+        case len(value) == 0 && rule.optional:
+        case len(value) == 0:
+          violations = append(violations, headerViolation{
+            Header: rule.name, Reason: "missing",
+          })
+        case rule.check != nil && !rule.check(value):
+          violations = append(violations, headerViolation{
+            Header: rule.name, Reason: fmt.Sprintf("expected %s", rule.format),
+          })
+        }
+      }
+      if len(violations) > 0 {
+        WriteResponse(w, http.StatusBadRequest, resHeaderError{
+          Error: "invalid request headers", Violations: violations,
+        })
+        return
+      }
+      next(w, r)
+    }
+  }
+}
@@ -0,0 +1,257 @@
+package userv
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type IdempotencyRecord struct {
+  // Request hash to detect a key reused with a different request
+  Fingerprint string `json:"fingerprint"`
+  Done bool `json:"done"`
+  StatusCode int `json:"statusCode"`
+  Header http.Header `json:"header"`
+  Body []byte `json:"body"`
+}
+
+// Reserve stores the in-progress record if the key is absent and returns nil,
+// otherwise returns the existing record
+type IdempotencyStore interface {
+  Reserve(
+    ctx context.Context, key string, rec *IdempotencyRecord, ttl time.Duration,
+  ) (*IdempotencyRecord, error)
+  Save(
+    ctx context.Context, key string, rec *IdempotencyRecord, ttl time.Duration,
+  ) error
+  Delete(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+  rec IdempotencyRecord
+  expiry time.Time
+}
+
+type MemoryIdempotencyStore struct {
+  mtx sync.Mutex
+  records map[string]idempotencyEntry
+  evictor Evictor
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+  return &MemoryIdempotencyStore{records: make(map[string]idempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(
+  ctx context.Context, key string, rec *IdempotencyRecord, ttl time.Duration,
+) (*IdempotencyRecord, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  EvictExpired(
+    &s.evictor, s.records, func(entry idempotencyEntry) time.Time {
+      return entry.expiry
+    },
+  )
+  now := time.Now()
+  entry, exist := s.records[key]
+  if exist && !now.After(entry.expiry) {
+    existing := entry.rec
+    return &existing, nil
+  }
+  s.records[key] = idempotencyEntry{rec: *rec, expiry: now.Add(ttl)}
+  return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(
+  ctx context.Context, key string, rec *IdempotencyRecord, ttl time.Duration,
+) error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  s.records[key] = idempotencyEntry{rec: *rec, expiry: time.Now().Add(ttl)}
+  return nil
+}
+
+func (s *MemoryIdempotencyStore) Delete(ctx context.Context, key string) error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  delete(s.records, key)
+  return nil
+}
+
+type idempotencyConfig struct {
+  header string
+  ttl time.Duration
+  lockTTL time.Duration
+  required bool
+}
+
+type idempotencyOption func(cfg *idempotencyConfig)
+
+func IdempotencyHeader(header string) idempotencyOption {
+  return func(cfg *idempotencyConfig) {
+    cfg.header = header
+  }
+}
+
+func IdempotencyTTL(ttl time.Duration) idempotencyOption {
+  return func(cfg *idempotencyConfig) {
+    cfg.ttl = ttl
+  }
+}
+
+// Expiry of the in-progress reservation in case the instance dies before
+// completing the request, 1 minute by default
+func IdempotencyLockTTL(ttl time.Duration) idempotencyOption {
+  return func(cfg *idempotencyConfig) {
+    cfg.lockTTL = ttl
+  }
+}
+
+// Rejects POST requests without the idempotency key
+func IdempotencyRequired() idempotencyOption {
+  return func(cfg *idempotencyConfig) {
+    cfg.required = true
+  }
+}
+
+// Records the status, body and the headers the handler sets, kept apart from
+// the headers of outer middlewares, which are per request e.g. the request ID
+type recordWriter struct {
+  http.ResponseWriter
+  header http.Header
+  statusCode int
+  body bytes.Buffer
+}
+
+func newRecordWriter(w http.ResponseWriter) *recordWriter {
+  return &recordWriter{ResponseWriter: w, header: make(http.Header)}
+}
+
+func (rw *recordWriter) Header() http.Header {
+  return rw.header
+}
+
+func (rw *recordWriter) WriteHeader(statusCode int) {
+  if rw.statusCode != 0 {
+    return
+  }
+  rw.statusCode = statusCode
+  for name, values := range rw.header {
+    rw.ResponseWriter.Header()[name] = values
+  }
+  rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *recordWriter) Unwrap() http.ResponseWriter {
+  return rw.ResponseWriter
+}
+
+func (rw *recordWriter) Write(body []byte) (int, error) {
+  if rw.statusCode == 0 {
+    rw.WriteHeader(http.StatusOK)
+  }
+  n, err := rw.ResponseWriter.Write(body)
+  rw.body.Write(body[:n])
+  return n, err
+}
+
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+  h := sha256.New()
+  _, _ = io.WriteString(h, r.Method + " " + r.URL.RequestURI() + "\n")
+  h.Write(body)
+  return hex.EncodeToString(h.Sum(nil))
+}
+
+// Replays the first response to a POST for retries with the same key, keys
+// are scoped by the principal when present. 5xx responses are not cached
+func Idempotency(
+  store IdempotencyStore, opts ...idempotencyOption,
+) func(next http.Handler) http.Handler {
+  cfg := &idempotencyConfig{
+    header: "Idempotency-Key", ttl: 24 * time.Hour, lockTTL: time.Minute,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      key := r.Header.Get(cfg.header)
+      if r.Method != http.MethodPost {
+        next.ServeHTTP(w, r)
+        return
+      }
+      if len(key) == 0 {
+        if cfg.required {
+          WriteError(w, BadRequest(cfg.header + " header is required"))
+          return
+        }
+        next.ServeHTTP(w, r)
+        return
+      }
+      if principal, exist := Principal(r.Context()); exist {
+        key = principal + "|" + key
+      }
+      body, err := io.ReadAll(r.Body)
+      if err != nil {
+        WriteError(w, BadRequest(err.Error()))
+        return
+      }
+      r.Body = io.NopCloser(bytes.NewReader(body))
+      fingerprint := idempotencyFingerprint(r, body)
+      rec, err := store.Reserve(
+        r.Context(), key, &IdempotencyRecord{Fingerprint: fingerprint},
+        cfg.lockTTL,
+      )
+      if err != nil {
+        WriteError(w, err)
+        return
+      }
+      switch {
+      case rec == nil:
+      case rec.Fingerprint != fingerprint:
+        WriteError(w, BadRequest(
+          cfg.header + " is already used for a different request",
+        ))
+        return
+      case !rec.Done:
+        WriteError(w, Conflict("request with the same " + cfg.header +
+          " is in progress"))
+        return
+      default:
+        for key, values := range rec.Header {
+          w.Header()[key] = values
+        }
+        w.Header().Set("Idempotent-Replayed", "true")
+        w.WriteHeader(rec.StatusCode)
+        _, _ = w.Write(rec.Body)
+        return
+      }
+      // The reservation is released when the handler panics or fails so
+      // the client can retry
+      completed := false
+      defer func() {
+        if !completed {
+          _ = store.Delete(context.WithoutCancel(r.Context()), key)
+        }
+      }()
+      rw := newRecordWriter(w)
+      next.ServeHTTP(rw, r)
+      if rw.statusCode == 0 {
+        rw.statusCode = http.StatusOK
+      }
+      if rw.statusCode >= 500 {
+        return
+      }
+      err = store.Save(context.WithoutCancel(r.Context()), key, &IdempotencyRecord{
+        Fingerprint: fingerprint, Done: true, StatusCode: rw.statusCode,
+        Header: rw.header.Clone(), Body: rw.body.Bytes(),
+      }, cfg.ttl)
+      completed = err == nil
+    })
+  }
+}
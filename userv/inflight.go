@@ -0,0 +1,111 @@
+package userv
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type inFlightConfig struct {
+  mux *http.ServeMux
+  queue int64
+  wait time.Duration
+  retryAfter time.Duration
+}
+
+type inFlightOption func(cfg *inFlightConfig)
+
+// Limits each mux route pattern separately instead of globally
+func InFlightPerRoute(mux *http.ServeMux) inFlightOption {
+  return func(cfg *inFlightConfig) {
+    cfg.mux = mux
+  }
+}
+
+func InFlightQueue(size int, wait time.Duration) inFlightOption {
+  return func(cfg *inFlightConfig) {
+    cfg.queue = int64(size)
+    cfg.wait = wait
+  }
+}
+
+func InFlightRetryAfter(retryAfter time.Duration) inFlightOption {
+  return func(cfg *inFlightConfig) {
+    cfg.retryAfter = retryAfter
+  }
+}
+
+type inFlightLimiter struct {
+  slots chan struct{}
+  waiting atomic.Int64
+}
+
+func (l *inFlightLimiter) acquire(
+  ctx context.Context, cfg *inFlightConfig,
+) bool {
+  select {
+  case l.slots <- struct{}{}:
+    return true
+  default:
+  }
+  if l.waiting.Add(1) > cfg.queue {
+    l.waiting.Add(-1)
+    return false
+  }
+  defer l.waiting.Add(-1)
+  timer := time.NewTimer(cfg.wait)
+  defer timer.Stop()
+  select {
+  case l.slots <- struct{}{}:
+    return true
+  case <-timer.C:
+    return false
+  case <-ctx.Done():
+    return false
+  }
+}
+
+func (l *inFlightLimiter) release() {
+  <-l.slots
+}
+
+func MaxInFlight(
+  limit int, opts ...inFlightOption,
+) func(next http.Handler) http.Handler {
+  cfg := &inFlightConfig{retryAfter: time.Second}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  retryAfter := strconv.Itoa(max(int(cfg.retryAfter.Seconds()), 1))
+  var mtx sync.Mutex
+  limiters := make(map[string]*inFlightLimiter)
+  limiter := func(r *http.Request) *inFlightLimiter {
+    var pattern string
+    if cfg.mux != nil {
+      _, pattern = cfg.mux.Handler(r)
+    }
+    mtx.Lock()
+    defer mtx.Unlock()
+    lim, exist := limiters[pattern]
+    if !exist {
+      lim = &inFlightLimiter{slots: make(chan struct{}, limit)}
+      limiters[pattern] = lim
+    }
+    return lim
+  }
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      lim := limiter(r)
+      if !lim.acquire(r.Context(), cfg) {
+        w.Header().Set("Retry-After", retryAfter)
+        WriteError(w, ServiceUnavailable("too many concurrent requests"))
+        return
+      }
+      defer lim.release()
+      next.ServeHTTP(w, r)
+    })
+  }
+}
@@ -0,0 +1,123 @@
+package userv
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// X-Forwarded-For is ignored unless the direct peer is a trusted proxy
+func SetTrustedProxies(proxies []netip.Prefix) {
+  trustedProxies.Store(&proxies)
+}
+
+func TrustedProxies() []netip.Prefix {
+  proxies := trustedProxies.Load()
+  if proxies == nil {
+    return nil
+  }
+  return *proxies
+}
+
+func ParseCIDRs(cidrs ...string) ([]netip.Prefix, error) {
+  prefixes := make([]netip.Prefix, 0, len(cidrs))
+  for _, cidr := range cidrs {
+    cidr = strings.TrimSpace(cidr)
+    if !strings.Contains(cidr, "/") {
+      addr, err := netip.ParseAddr(cidr)
+      if err != nil {
+        return nil, err
+      }
+      prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+      continue
+    }
+    prefix, err := netip.ParsePrefix(cidr)
+    if err != nil {
+      return nil, err
+    }
+    prefixes = append(prefixes, prefix.Masked())
+  }
+  return prefixes, nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+  for _, prefix := range prefixes {
+    if prefix.Contains(addr) {
+      return true
+    }
+  }
+  return false
+}
+
+func parseAddr(ip string) (netip.Addr, bool) {
+  ip = strings.TrimSpace(ip)
+  addrPort, err := netip.ParseAddrPort(ip)
+  if err == nil {
+    return addrPort.Addr().Unmap(), true
+  }
+  addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+  if err == nil {
+    return addr.Unmap(), true
+  }
+  return netip.Addr{}, false
+}
+
+// The X-Forwarded-For chain is walked right to left skipping trusted proxies
+func clientAddr(r *http.Request, proxies []netip.Prefix) (netip.Addr, bool) {
+  peer, valid := parseAddr(r.RemoteAddr)
+  if !valid || !prefixesContain(proxies, peer) {
+    return peer, valid
+  }
+  hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+  for i := len(hops) - 1; i >= 0; i-- {
+    hop, valid := parseAddr(hops[i])
+    if !valid {
+      break
+    }
+    peer = hop
+    if !prefixesContain(proxies, hop) {
+      break
+    }
+  }
+  return peer, true
+}
+
+type ipFilterConfig struct {
+  proxies []netip.Prefix
+}
+
+type ipFilterOption func(cfg *ipFilterConfig)
+
+func IPTrustedProxies(proxies []netip.Prefix) ipFilterOption {
+  return func(cfg *ipFilterConfig) {
+    cfg.proxies = proxies
+  }
+}
+
+func IPFilter(
+  allow, deny []netip.Prefix, opts ...ipFilterOption,
+) Middleware {
+  cfg := &ipFilterConfig{proxies: TrustedProxies()}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      addr, valid := clientAddr(r, cfg.proxies)
+      if !valid {
+        WriteError(w, Forbidden("invalid client IP"))
+        return
+      }
+      if prefixesContain(deny, addr) ||
+        len(allow) > 0 && !prefixesContain(allow, addr) {
+        WriteError(w, Forbidden(fmt.Sprintf("client IP %s is not allowed", addr)))
+        return
+      }
+      next(w, r)
+    }
+  }
+}
@@ -0,0 +1,127 @@
+package userv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type lifecycleTask struct {
+  name string
+  run func(ctx context.Context) error
+  stop func(ctx context.Context) error
+  cancel context.CancelFunc
+  done chan struct{}
+}
+
+type Lifecycle struct {
+  mtx sync.Mutex
+  timeout time.Duration
+  tasks []*lifecycleTask
+  started bool
+}
+
+// Tasks start in registration order and stop in reverse order, each one
+// within the timeout
+func NewLifecycle(timeout time.Duration) *Lifecycle {
+  return &Lifecycle{timeout: timeout}
+}
+
+func (l *Lifecycle) startTask(ctx context.Context, task *lifecycleTask) {
+  if task.run == nil {
+    return
+  }
+  ctx, task.cancel = context.WithCancel(context.WithoutCancel(ctx))
+  task.done = make(chan struct{})
+  go func() {
+    defer close(task.done)
+    start := time.Now()
+    err := task.run(ctx)
+    if err != nil && !errors.Is(err, context.Canceled) {
+      LogAction("background", err, start, task.name)
+    }
+  }()
+}
+
+// Background loops run until their context is canceled on shutdown
+func (l *Lifecycle) Background(
+  name string, run func(ctx context.Context) error,
+) {
+  l.mtx.Lock()
+  defer l.mtx.Unlock()
+  task := &lifecycleTask{name: name, run: run}
+  l.tasks = append(l.tasks, task)
+  if l.started {
+    l.startTask(context.Background(), task)
+  }
+}
+
+func (l *Lifecycle) OnShutdown(
+  name string, stop func(ctx context.Context) error,
+) {
+  l.mtx.Lock()
+  defer l.mtx.Unlock()
+  l.tasks = append(l.tasks, &lifecycleTask{name: name, stop: stop})
+}
+
+func (l *Lifecycle) Start(ctx context.Context) {
+  l.mtx.Lock()
+  defer l.mtx.Unlock()
+  if l.started {
+    return
+  }
+  l.started = true
+  for _, task := range l.tasks {
+    l.startTask(ctx, task)
+  }
+}
+
+func (l *Lifecycle) stopTask(ctx context.Context, task *lifecycleTask) error {
+  ctx, cancel := context.WithTimeout(ctx, l.timeout)
+  defer cancel()
+  if task.cancel != nil {
+    task.cancel()
+    select {
+    case <-task.done:
+    case <-ctx.Done():
+      return fmt.Errorf("%s: stop timeout", task.name)
+    }
+  }
+  if task.stop != nil {
+    err := task.stop(ctx)
+    if err != nil {
+      return fmt.Errorf("%s: %w", task.name, err)
+    }
+  }
+  return nil
+}
+
+func (l *Lifecycle) Stop(ctx context.Context) error {
+  l.mtx.Lock()
+  tasks := slices.Clone(l.tasks)
+  l.started = false
+  l.mtx.Unlock()
+  var errs []error
+  for _, task := range slices.Backward(tasks) {
+    start := time.Now()
+    err := l.stopTask(ctx, task)
+    LogAction("shutdown", err, start, task.name)
+    if err != nil {
+      errs = append(errs, err)
+    }
+  }
+  return errors.Join(errs...)
+}
+
+var defaultLifecycle = NewLifecycle(10 * time.Second)
+
+func Background(name string, run func(ctx context.Context) error) {
+  defaultLifecycle.Background(name, run)
+}
+
+func OnShutdown(name string, stop func(ctx context.Context) error) {
+  defaultLifecycle.OnShutdown(name, stop)
+}
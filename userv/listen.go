@@ -0,0 +1,110 @@
+package userv
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+)
+
+type listenFunc func() (net.Listener, error)
+
+// Listens on every address e.g. 0.0.0.0:8080 and [::]:8080 for dual-stack,
+// IPv6 addresses are bound IPv6-only to not conflict with IPv4
+func ServerListen(addrs ...string) serverOption {
+  return func(cfg *serverConfig) {
+    for _, addr := range addrs {
+      network := "tcp"
+      host, _, _ := net.SplitHostPort(addr)
+      ip, err := netip.ParseAddr(host)
+      if err == nil {
+        network = "tcp4"
+        if ip.Is6() {
+          network = "tcp6"
+        }
+      }
+      cfg.listeners = append(cfg.listeners, func() (net.Listener, error) {
+        return net.Listen(network, addr)
+      })
+    }
+  }
+}
+
+// Replaces a stale socket file and sets the socket permissions
+func ServerUnix(path string, mode os.FileMode) serverOption {
+  return func(cfg *serverConfig) {
+    cfg.listeners = append(cfg.listeners, func() (net.Listener, error) {
+      err := os.Remove(path)
+      if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return nil, err
+      }
+      l, err := net.Listen("unix", path)
+      if err != nil {
+        return nil, err
+      }
+      err = os.Chmod(path, mode)
+      if err != nil {
+        _ = l.Close()
+        return nil, err
+      }
+      return l, nil
+    })
+  }
+}
+
+// Inherits a listening socket passed as a file descriptor
+func ServerFD(fd uintptr) serverOption {
+  return func(cfg *serverConfig) {
+    cfg.listeners = append(cfg.listeners, func() (net.Listener, error) {
+      file := os.NewFile(fd, "fd" + strconv.Itoa(int(fd)))
+      if file == nil {
+        return nil, fmt.Errorf("invalid listener fd %d", fd)
+      }
+      defer func() {
+        _ = file.Close()
+      }()
+      return net.FileListener(file)
+    })
+  }
+}
+
+// systemd socket activation passes LISTEN_FDS sockets starting at fd 3
+const systemdFDStart = 3
+
+// Inherits the sockets of systemd socket activation
+func ServerSystemd() serverOption {
+  return func(cfg *serverConfig) {
+    pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+    n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+    if pid != os.Getpid() || n == 0 {
+      cfg.listeners = append(cfg.listeners, func() (net.Listener, error) {
+        return nil, errors.New("no systemd socket activation listeners")
+      })
+      return
+    }
+    // Not inherited by child processes
+    _ = os.Unsetenv("LISTEN_PID")
+    _ = os.Unsetenv("LISTEN_FDS")
+    _ = os.Unsetenv("LISTEN_FDNAMES")
+    for fd := range n {
+      ServerFD(uintptr(systemdFDStart + fd))(cfg)
+    }
+  }
+}
+
+func openListeners(listens []listenFunc) ([]net.Listener, error) {
+  listeners := make([]net.Listener, 0, len(listens))
+  for _, listen := range listens {
+    l, err := listen()
+    if err != nil {
+      for _, l := range listeners {
+        _ = l.Close()
+      }
+      return nil, err
+    }
+    listeners = append(listeners, l)
+  }
+  return listeners, nil
+}
@@ -0,0 +1,63 @@
+package userv
+
+import (
+	"encoding/json"
+)
+
+type capBuffer struct {
+  buf []byte
+  limit int
+  truncated bool
+}
+
+func (c *capBuffer) Write(p []byte) (int, error) {
+  room := c.limit - len(c.buf)
+  if len(p) > room {
+    c.truncated = true
+    p = p[:max(room, 0)]
+  }
+  c.buf = append(c.buf, p...)
+  return len(p), nil
+}
+
+func redactValue(val any, redact map[string]bool) any {
+  switch v := val.(type) {
+  case map[string]any:
+    for key, item := range v {
+      if redact[key] {
+        v[key] = "***"
+        continue
+      }
+      v[key] = redactValue(item, redact)
+    }
+  case []any:
+    for i, item := range v {
+      v[i] = redactValue(item, redact)
+    }
+  }
+  return val
+}
+
+// Valid JSON is redacted and kept as JSON, anything else as a string
+func logBody(c *capBuffer, redact map[string]bool) any {
+  if len(c.buf) == 0 {
+    return nil
+  }
+  if !c.truncated {
+    var val any
+    err := json.Unmarshal(c.buf, &val)
+    if err == nil {
+      jval, err := json.Marshal(redactValue(val, redact))
+      if err == nil {
+        return json.RawMessage(jval)
+      }
+    }
+  }
+  if len(redact) > 0 {
+    return "[unparsed body omitted]"
+  }
+  if c.truncated {
+    return string(c.buf) + "..."
+  }
+  return string(c.buf)
+}
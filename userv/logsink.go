@@ -0,0 +1,222 @@
+package userv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type multiSink []io.Writer
+
+// Every sink receives every write regardless of failures of other sinks
+func (m multiSink) Write(p []byte) (int, error) {
+  var errs []error
+  for _, w := range m {
+    _, err := w.Write(p)
+    if err != nil {
+      errs = append(errs, err)
+    }
+  }
+  if len(errs) > 0 {
+    return len(p), fmt.Errorf("log sink: %w", errs[0])
+  }
+  return len(p), nil
+}
+
+type AsyncWriter struct {
+  w io.Writer
+  queue chan []byte
+  done chan struct{}
+  dropped atomic.Uint64
+  mtx sync.Mutex
+  closed bool
+}
+
+// Writes never block: when the buffer is full records are dropped and counted
+func NewAsyncWriter(w io.Writer, buffer int) *AsyncWriter {
+  a := &AsyncWriter{
+    w: w,
+    queue: make(chan []byte, buffer),
+    done: make(chan struct{}),
+  }
+  go func() {
+    defer close(a.done)
+    for p := range a.queue {
+      _, _ = a.w.Write(p)
+    }
+  }()
+  return a
+}
+
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+  buf := slices.Clone(p)
+  a.mtx.Lock()
+  defer a.mtx.Unlock()
+  if a.closed {
+    return 0, errors.New("log sink: write to closed async writer")
+  }
+  select {
+  case a.queue <- buf:
+  default:
+    a.dropped.Add(1)
+  }
+  return len(p), nil
+}
+
+func (a *AsyncWriter) Dropped() uint64 {
+  return a.dropped.Load()
+}
+
+// Flushes buffered records
+func (a *AsyncWriter) Close() error {
+  a.mtx.Lock()
+  if !a.closed {
+    a.closed = true
+    close(a.queue)
+  }
+  a.mtx.Unlock()
+  <-a.done
+  closer, isCloser := a.w.(io.Closer)
+  if isCloser {
+    return closer.Close()
+  }
+  return nil
+}
+
+type RotatingFile struct {
+  mtx sync.Mutex
+  path string
+  maxSize int64
+  maxAge time.Duration
+  maxFiles int
+  file *os.File
+  size int64
+  opened time.Time
+}
+
+// Rotated files are renamed to path.<timestamp>, only maxFiles are kept
+func NewRotatingFile(
+  path string, maxSize int64, maxAge time.Duration, maxFiles int,
+) (*RotatingFile, error) {
+  rf := &RotatingFile{
+    path: path, maxSize: maxSize, maxAge: maxAge, maxFiles: maxFiles,
+  }
+  err := rf.open()
+  if err != nil {
+    return nil, err
+  }
+  return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+  err := os.MkdirAll(filepath.Dir(rf.path), 0o750)
+  if err != nil {
+    return err
+  }
+  file, err := os.OpenFile(rf.path, os.O_CREATE | os.O_WRONLY | os.O_APPEND, 0o640)
+  if err != nil {
+    return err
+  }
+  info, err := file.Stat()
+  if err != nil {
+    _ = file.Close()
+    return err
+  }
+  rf.file, rf.size, rf.opened = file, info.Size(), time.Now()
+  return nil
+}
+
+// The current file is reopened on every path so a failed rotation does not
+// break later writes
+func (rf *RotatingFile) rotate() error {
+  err := rf.file.Close()
+  rf.file = nil
+  if err != nil {
+    return errors.Join(err, rf.open())
+  }
+  stamp := time.Now().UTC().Format("20060102T150405.000000")
+  err = os.Rename(rf.path, fmt.Sprintf("%s.%s", rf.path, stamp))
+  if err != nil {
+    return errors.Join(err, rf.open())
+  }
+  err = rf.open()
+  if err != nil {
+    return err
+  }
+  rotated, err := filepath.Glob(rf.path + ".*")
+  if err != nil {
+    return err
+  }
+  slices.Sort(rotated)
+  for len(rotated) > rf.maxFiles {
+    _ = os.Remove(rotated[0])
+    rotated = rotated[1:]
+  }
+  return nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+  rf.mtx.Lock()
+  defer rf.mtx.Unlock()
+  if rf.file == nil {
+    err := rf.open()
+    if err != nil {
+      return 0, err
+    }
+  }
+  if rf.size > 0 && (rf.maxSize > 0 && rf.size + int64(len(p)) > rf.maxSize ||
+    rf.maxAge > 0 && time.Since(rf.opened) > rf.maxAge) {
+    err := rf.rotate()
+    if err != nil && rf.file == nil {
+      return 0, err
+    }
+  }
+  n, err := rf.file.Write(p)
+  rf.size += int64(n)
+  return n, err
+}
+
+func (rf *RotatingFile) Close() error {
+  rf.mtx.Lock()
+  defer rf.mtx.Unlock()
+  if rf.file == nil {
+    return nil
+  }
+  return rf.file.Close()
+}
+
+type syslogSink struct {
+  conn net.Conn
+  tag string
+  host string
+}
+
+// RFC 3164 messages over udp or tcp, facility local0, severity info
+func NewSyslogSink(network, addr, tag string) (io.WriteCloser, error) {
+  conn, err := net.Dial(network, addr)
+  if err != nil {
+    return nil, err
+  }
+  host, _ := os.Hostname()
+  return &syslogSink{conn: conn, tag: tag, host: host}, nil
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+  stamp := time.Now().Format(time.Stamp)
+  _, err := fmt.Fprintf(s.conn, "<134>%s %s %s: %s", stamp, s.host, s.tag, p)
+  if err != nil {
+    return 0, err
+  }
+  return len(p), nil
+}
+
+func (s *syslogSink) Close() error {
+  return s.conn.Close()
+}
@@ -0,0 +1,72 @@
+package userv
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type maintenanceConfig struct {
+  retryAfter time.Duration
+  body any
+  mux *http.ServeMux
+  patterns []string
+}
+
+type maintenanceOption func(cfg *maintenanceConfig)
+
+func MaintenanceRetryAfter(retryAfter time.Duration) maintenanceOption {
+  return func(cfg *maintenanceConfig) {
+    cfg.retryAfter = retryAfter
+  }
+}
+
+// Custom response body instead of the default error
+func MaintenanceBody(body any) maintenanceOption {
+  return func(cfg *maintenanceConfig) {
+    cfg.body = body
+  }
+}
+
+// Limits maintenance to the mux route patterns instead of all routes
+func MaintenanceRoutes(
+  mux *http.ServeMux, patterns ...string,
+) maintenanceOption {
+  return func(cfg *maintenanceConfig) {
+    cfg.mux = mux
+    cfg.patterns = patterns
+  }
+}
+
+// enabled is checked on every request e.g. var maint atomic.Bool;
+// userv.Maintenance(maint.Load)
+func Maintenance(
+  enabled func() bool, opts ...maintenanceOption,
+) func(next http.Handler) http.Handler {
+  cfg := &maintenanceConfig{
+    retryAfter: time.Minute,
+    body: resError{Error: "service is under maintenance"},
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  retryAfter := strconv.Itoa(max(int(cfg.retryAfter.Seconds()), 1))
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      if !enabled() {
+        next.ServeHTTP(w, r)
+        return
+      }
+      if cfg.mux != nil {
+        _, pattern := cfg.mux.Handler(r)
+        if !slices.Contains(cfg.patterns, pattern) {
+          next.ServeHTTP(w, r)
+          return
+        }
+      }
+      w.Header().Set("Retry-After", retryAfter)
+      WriteResponse(w, http.StatusServiceUnavailable, cfg.body)
+    })
+  }
+}
@@ -0,0 +1,111 @@
+package userv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+)
+
+func msgpackLen(buf *bytes.Buffer, n int, fix, fixMax byte, b16, b32 byte) {
+  switch {
+  case n < int(fixMax):
+    buf.WriteByte(fix | byte(n))
+  case n <= math.MaxUint16:
+    buf.WriteByte(b16)
+    _ = binary.Write(buf, binary.BigEndian, uint16(n))
+  default:
+    buf.WriteByte(b32)
+    _ = binary.Write(buf, binary.BigEndian, uint32(n))
+  }
+}
+
+func msgpackEncode(buf *bytes.Buffer, val any) error {
+  switch v := val.(type) {
+  case nil:
+    buf.WriteByte(0xc0)
+  case bool:
+    if v {
+      buf.WriteByte(0xc3)
+    } else {
+      buf.WriteByte(0xc2)
+    }
+  case json.Number:
+    i, err := v.Int64()
+    if err != nil {
+      f, err := v.Float64()
+      if err != nil {
+        return err
+      }
+      buf.WriteByte(0xcb)
+      _ = binary.Write(buf, binary.BigEndian, f)
+      return nil
+    }
+    if i >= -32 && i < 128 {
+      buf.WriteByte(byte(i))
+      return nil
+    }
+    buf.WriteByte(0xd3)
+    _ = binary.Write(buf, binary.BigEndian, i)
+  case string:
+    if len(v) < 32 {
+      buf.WriteByte(0xa0 | byte(len(v)))
+    } else {
+      if len(v) <= math.MaxUint8 {
+        buf.WriteByte(0xd9)
+        buf.WriteByte(byte(len(v)))
+      } else {
+        msgpackLen(buf, len(v), 0, 0, 0xda, 0xdb)
+      }
+    }
+    buf.WriteString(v)
+  case []any:
+    msgpackLen(buf, len(v), 0x90, 16, 0xdc, 0xdd)
+    for _, item := range v {
+      err := msgpackEncode(buf, item)
+      if err != nil {
+        return err
+      }
+    }
+  case map[string]any:
+    msgpackLen(buf, len(v), 0x80, 16, 0xde, 0xdf)
+    keys := make([]string, 0, len(v))
+    for key := range v {
+      keys = append(keys, key)
+    }
+    slices.Sort(keys)
+    for _, key := range keys {
+      _ = msgpackEncode(buf, key)
+      err := msgpackEncode(buf, v[key])
+      if err != nil {
+        return err
+      }
+    }
+  default:
+    return fmt.Errorf("msgpack: unsupported type %T", val)
+  }
+  return nil
+}
+
+// Values are encoded through their JSON representation to honor json tags
+func MsgpackMarshal(val any) ([]byte, error) {
+  jval, err := json.Marshal(val)
+  if err != nil {
+    return nil, err
+  }
+  dec := json.NewDecoder(bytes.NewReader(jval))
+  dec.UseNumber()
+  var gen any
+  err = dec.Decode(&gen)
+  if err != nil {
+    return nil, err
+  }
+  var buf bytes.Buffer
+  err = msgpackEncode(&buf, gen)
+  if err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
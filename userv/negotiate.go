@@ -0,0 +1,168 @@
+package userv
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const appJSON = "application/json"
+
+type Encoder func(val any) ([]byte, error)
+
+var (
+  encodersMtx sync.RWMutex
+  encoders = map[string]Encoder{
+    appJSON: json.Marshal,
+    "application/xml": xml.Marshal,
+    "text/xml": xml.Marshal,
+    "application/msgpack": MsgpackMarshal,
+    "application/x-msgpack": MsgpackMarshal,
+  }
+)
+
+func RegisterEncoder(contentType string, enc Encoder) {
+  encodersMtx.Lock()
+  defer encodersMtx.Unlock()
+  encoders[contentType] = enc
+}
+
+type acceptRange struct {
+  mediaType string
+  q float64
+}
+
+func parseAccept(accept string) []acceptRange {
+  var ranges []acceptRange
+  for part := range strings.SplitSeq(accept, ",") {
+    params := strings.Split(part, ";")
+    mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+    if len(mediaType) == 0 {
+      continue
+    }
+    q := 1.0
+    for _, param := range params[1:] {
+      key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+      if key == "q" {
+        pq, err := strconv.ParseFloat(value, 64)
+        if err == nil {
+          q = pq
+        }
+      }
+    }
+    if q > 0 {
+      ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+    }
+  }
+  slices.SortStableFunc(ranges, func(a, b acceptRange) int {
+    switch {
+    case a.q > b.q:
+      return -1
+    case a.q < b.q:
+      return 1
+    default:
+      return 0
+    }
+  })
+  return ranges
+}
+
+func negotiate(accept string) (string, Encoder, bool) {
+  if len(strings.TrimSpace(accept)) == 0 {
+    return appJSON, json.Marshal, true
+  }
+  encodersMtx.RLock()
+  defer encodersMtx.RUnlock()
+  for _, ar := range parseAccept(accept) {
+    switch ar.mediaType {
+    case "*/*", "application/*":
+      return appJSON, json.Marshal, true
+    }
+    enc, exist := encoders[ar.mediaType]
+    if exist {
+      return ar.mediaType, enc, true
+    }
+  }
+  return "", nil, false
+}
+
+// Values the negotiated encoder cannot represent e.g. maps in XML fall back
+// to JSON
+func encodeResponse(
+  contentType string, encode Encoder, res any,
+) (string, []byte, error) {
+  eres, err := encode(res)
+  if err != nil && contentType != appJSON {
+    contentType = appJSON
+    eres, err = json.Marshal(res)
+  }
+  return contentType, eres, err
+}
+
+// The encoder registered for the media type of the ContentType option
+func contentTypeEncoder(contentType string) (string, Encoder, error) {
+  mediaType, _, _ := strings.Cut(contentType, ";")
+  mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+  encodersMtx.RLock()
+  defer encodersMtx.RUnlock()
+  enc, exist := encoders[mediaType]
+  if !exist {
+    return "", nil, fmt.Errorf("no encoder registered for %s", contentType)
+  }
+  return contentType, enc, nil
+}
+
+func supportedMediaTypes() string {
+  encodersMtx.RLock()
+  defer encodersMtx.RUnlock()
+  return strings.Join(slices.Sorted(maps.Keys(encoders)), ", ")
+}
+
+type negotiateWriter struct {
+  http.ResponseWriter
+  contentType string
+  encode Encoder
+}
+
+func (n *negotiateWriter) Unwrap() http.ResponseWriter {
+  return n.ResponseWriter
+}
+
+func responseEncoder(w http.ResponseWriter) (string, Encoder) {
+  for {
+    switch t := w.(type) {
+    case *negotiateWriter:
+      return t.contentType, t.encode
+    case interface{ Unwrap() http.ResponseWriter }:
+      w = t.Unwrap()
+    default:
+      return appJSON, json.Marshal
+    }
+  }
+}
+
+// WriteResponse and WriteError encode with the media type negotiated here
+func Negotiate() func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      contentType, encode, valid := negotiate(r.Header.Get("Accept"))
+      if !valid {
+        WriteError(w, NotAcceptable(
+          "not acceptable: supported media types are " + supportedMediaTypes(),
+        ))
+        return
+      }
+      w.Header().Add("Vary", "Accept")
+      nw := &negotiateWriter{
+        ResponseWriter: w, contentType: contentType, encode: encode,
+      }
+      next.ServeHTTP(nw, r)
+    })
+  }
+}
@@ -0,0 +1,335 @@
+package userv
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type routeConfig struct {
+  summary string
+  tags []string
+  request reflect.Type
+  responses map[int]reflect.Type
+  errors []int
+}
+
+type routeOption func(cfg *routeConfig)
+
+func RouteSummary(summary string) routeOption {
+  return func(cfg *routeConfig) {
+    cfg.summary = summary
+  }
+}
+
+func RouteTags(tags ...string) routeOption {
+  return func(cfg *routeConfig) {
+    cfg.tags = append(cfg.tags, tags...)
+  }
+}
+
+// JSON request body read with ReadBody[T]
+func RouteRequest[T any]() routeOption {
+  return func(cfg *routeConfig) {
+    cfg.request = reflect.TypeFor[T]()
+  }
+}
+
+func RouteResponse[T any](statusCode int) routeOption {
+  return func(cfg *routeConfig) {
+    cfg.responses[statusCode] = reflect.TypeFor[T]()
+  }
+}
+
+// Error status codes written with WriteError
+func RouteErrors(statusCodes ...int) routeOption {
+  return func(cfg *routeConfig) {
+    cfg.errors = append(cfg.errors, statusCodes...)
+  }
+}
+
+type apiRoute struct {
+  method string
+  path string
+  cfg *routeConfig
+}
+
+type apiSpec struct {
+  title string
+  version string
+  mtx sync.Mutex
+  routes []apiRoute
+}
+
+// Route group on a mux that records declared request, response and error
+// types for the OpenAPI document
+type API struct {
+  mux *http.ServeMux
+  prefix string
+  mws []Middleware
+  spec *apiSpec
+}
+
+func NewAPI(mux *http.ServeMux, title, version string) *API {
+  return &API{mux: mux, spec: &apiSpec{title: title, version: version}}
+}
+
+// Shares the registry, prefixes paths and applies middlewares in order
+func (a *API) Group(prefix string, mws ...Middleware) *API {
+  return &API{
+    mux: a.mux, prefix: a.prefix + prefix,
+    mws: append(slices.Clone(a.mws), mws...), spec: a.spec,
+  }
+}
+
+// Pattern is "METHOD /path/{param}", the method defaults to GET
+func (a *API) Handle(
+  pattern string, handler http.HandlerFunc, opts ...routeOption,
+) {
+  cfg := &routeConfig{responses: make(map[int]reflect.Type)}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  method, path, found := strings.Cut(pattern, " ")
+  if !found {
+    method, path = http.MethodGet, pattern
+  }
+  path = a.prefix + strings.TrimSpace(path)
+  for i := len(a.mws) - 1; i >= 0; i-- {
+    handler = a.mws[i](handler)
+  }
+  a.mux.HandleFunc(method + " " + path, handler)
+  a.spec.mtx.Lock()
+  defer a.spec.mtx.Unlock()
+  a.spec.routes = append(
+    a.spec.routes, apiRoute{method: method, path: path, cfg: cfg},
+  )
+}
+
+var (
+  // The {$} end-of-path marker is not a parameter
+  pathParamRe = regexp.MustCompile(`\{([^}.$][^}.]*)(\.\.\.)?\}`)
+  schemaNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+  timeType = reflect.TypeFor[time.Time]()
+)
+
+type schemaGen struct {
+  components map[string]any
+  names map[reflect.Type]string
+  types map[string]reflect.Type
+}
+
+// Package-qualified e.g. api.Error, the full package path when two packages
+// share the name
+func (g *schemaGen) componentName(typ reflect.Type) string {
+  name, exist := g.names[typ]
+  if exist {
+    return name
+  }
+  name = schemaNameRe.ReplaceAllString(
+    path.Base(typ.PkgPath()) + "." + typ.Name(), "_",
+  )
+  _, taken := g.types[name]
+  if taken {
+    name = schemaNameRe.ReplaceAllString(
+      typ.PkgPath() + "." + typ.Name(), "_",
+    )
+  }
+  g.names[typ], g.types[name] = name, typ
+  return name
+}
+
+func (g *schemaGen) schema(typ reflect.Type) map[string]any {
+  for typ.Kind() == reflect.Pointer {
+    typ = typ.Elem()
+  }
+  if typ == timeType {
+    return map[string]any{"type": "string", "format": "date-time"}
+  }
+  switch typ.Kind() {
+  case reflect.Bool:
+    return map[string]any{"type": "boolean"}
+  case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8,
+    reflect.Uint16:
+    return map[string]any{"type": "integer", "format": "int32"}
+  case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32,
+    reflect.Uint64:
+    return map[string]any{"type": "integer", "format": "int64"}
+  case reflect.Float32, reflect.Float64:
+    return map[string]any{"type": "number"}
+  case reflect.String:
+    return map[string]any{"type": "string"}
+  case reflect.Slice, reflect.Array:
+    if typ.Elem().Kind() == reflect.Uint8 {
+      return map[string]any{"type": "string", "format": "byte"}
+    }
+    return map[string]any{"type": "array", "items": g.schema(typ.Elem())}
+  case reflect.Map:
+    return map[string]any{
+      "type": "object", "additionalProperties": g.schema(typ.Elem()),
+    }
+  case reflect.Struct:
+    if len(typ.Name()) == 0 {
+      return g.object(typ)
+    }
+    name := g.componentName(typ)
+    _, exist := g.components[name]
+    if !exist {
+      g.components[name] = nil // recursive types
+      g.components[name] = g.object(typ)
+    }
+    return map[string]any{"$ref": "#/components/schemas/" + name}
+  }
+  return map[string]any{}
+}
+
+// Properties follow json tags, required follows check:"required"
+func (g *schemaGen) object(typ reflect.Type) map[string]any {
+  props := make(map[string]any)
+  var required []string
+  for i := range typ.NumField() {
+    field := typ.Field(i)
+    if !field.IsExported() {
+      continue
+    }
+    name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+    if name == "-" {
+      continue
+    }
+    ftyp := field.Type
+    for ftyp.Kind() == reflect.Pointer {
+      ftyp = ftyp.Elem()
+    }
+    if field.Anonymous && len(name) == 0 && ftyp.Kind() == reflect.Struct {
+      embedded := g.object(ftyp)
+      for key, prop := range embedded["properties"].(map[string]any) {
+        props[key] = prop
+      }
+      req, _ := embedded["required"].([]string)
+      required = append(required, req...)
+      continue
+    }
+    if len(name) == 0 {
+      name = field.Name
+    }
+    schema := g.schema(field.Type)
+    if strings.Contains(opts, "string") {
+      schema = map[string]any{"type": "string"}
+    }
+    props[name] = schema
+    rules := strings.Split(field.Tag.Get("check"), ",")
+    if slices.Contains(rules, "required") {
+      required = append(required, name)
+    }
+  }
+  obj := map[string]any{"type": "object", "properties": props}
+  if len(required) > 0 {
+    obj["required"] = required
+  }
+  return obj
+}
+
+var errorSchema = map[string]any{
+  "type": "object", "required": []string{"error"},
+  "properties": map[string]any{"error": map[string]any{"type": "string"}},
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+  return map[string]any{appJSON: map[string]any{"schema": schema}}
+}
+
+func (g *schemaGen) operation(route apiRoute) map[string]any {
+  op := make(map[string]any)
+  if len(route.cfg.summary) > 0 {
+    op["summary"] = route.cfg.summary
+  }
+  if len(route.cfg.tags) > 0 {
+    op["tags"] = route.cfg.tags
+  }
+  var params []any
+  for _, match := range pathParamRe.FindAllStringSubmatch(route.path, -1) {
+    params = append(params, map[string]any{
+      "name": match[1], "in": "path", "required": true,
+      "schema": map[string]any{"type": "string"},
+    })
+  }
+  if len(params) > 0 {
+    op["parameters"] = params
+  }
+  if route.cfg.request != nil {
+    op["requestBody"] = map[string]any{
+      "required": true, "content": jsonContent(g.schema(route.cfg.request)),
+    }
+  }
+  responses := make(map[string]any)
+  for statusCode, typ := range route.cfg.responses {
+    res := map[string]any{"description": http.StatusText(statusCode)}
+    if bodyAllowed(statusCode) && typ != nil {
+      res["content"] = jsonContent(g.schema(typ))
+    }
+    responses[strconv.Itoa(statusCode)] = res
+  }
+  for _, statusCode := range route.cfg.errors {
+    responses[strconv.Itoa(statusCode)] = map[string]any{
+      "description": http.StatusText(statusCode),
+      "content": jsonContent(errorSchema),
+    }
+  }
+  if len(responses) == 0 {
+    responses["default"] = map[string]any{"description": "Response"}
+  }
+  op["responses"] = responses
+  return op
+}
+
+// OpenAPI 3 document of the registered routes
+func (a *API) OpenAPI() map[string]any {
+  a.spec.mtx.Lock()
+  routes := slices.Clone(a.spec.routes)
+  a.spec.mtx.Unlock()
+  g := &schemaGen{
+    components: make(map[string]any),
+    names: make(map[reflect.Type]string), types: make(map[string]reflect.Type),
+  }
+  paths := make(map[string]any)
+  for _, route := range routes {
+    path := pathParamRe.ReplaceAllString(
+      strings.TrimSuffix(route.path, "{$}"), "{$1}",
+    )
+    item, exist := paths[path].(map[string]any)
+    if !exist {
+      item = make(map[string]any)
+      paths[path] = item
+    }
+    item[strings.ToLower(route.method)] = g.operation(route)
+  }
+  doc := map[string]any{
+    "openapi": "3.0.3",
+    "info": map[string]any{"title": a.spec.title, "version": a.spec.version},
+    "paths": paths,
+  }
+  if len(g.components) > 0 {
+    doc["components"] = map[string]any{"schemas": g.components}
+  }
+  return doc
+}
+
+// Serves the document e.g. mux.Handle("GET /openapi.json", api.Handler())
+func (a *API) Handler() http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    doc, err := json.Marshal(a.OpenAPI())
+    if err != nil {
+      WriteError(w, InternalServerError(err.Error()))
+      return
+    }
+    w.Header().Set("Content-Type", appJSON)
+    _, _ = w.Write(doc)
+  }
+}
@@ -0,0 +1,223 @@
+package userv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type QuotaPeriod int
+
+const (
+  QuotaDaily QuotaPeriod = iota
+  QuotaMonthly
+)
+
+func (p QuotaPeriod) String() string {
+  if p == QuotaMonthly {
+    return "monthly"
+  }
+  return "daily"
+}
+
+// UTC window start and the next window start
+func (p QuotaPeriod) window(now time.Time) (time.Time, time.Time) {
+  now = now.UTC()
+  if p == QuotaMonthly {
+    start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+    return start, start.AddDate(0, 1, 0)
+  }
+  start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+  return start, start.AddDate(0, 0, 1)
+}
+
+// Counters are keyed by principal and window, expired counters can be dropped
+type QuotaStore interface {
+  Incr(ctx context.Context, key string, expiry time.Time) (int64, error)
+  Get(ctx context.Context, key string) (int64, error)
+  Reset(ctx context.Context, key string) error
+}
+
+type quotaEntry struct {
+  count int64
+  expiry time.Time
+}
+
+type MemoryQuotaStore struct {
+  mtx sync.Mutex
+  counters map[string]quotaEntry
+  evictor Evictor
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+  return &MemoryQuotaStore{counters: make(map[string]quotaEntry)}
+}
+
+func (s *MemoryQuotaStore) Incr(
+  ctx context.Context, key string, expiry time.Time,
+) (int64, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  EvictExpired(&s.evictor, s.counters, func(entry quotaEntry) time.Time {
+    return entry.expiry
+  })
+  entry, exist := s.counters[key]
+  if !exist || time.Now().After(entry.expiry) {
+    entry = quotaEntry{}
+  }
+  entry.count++
+  entry.expiry = expiry
+  s.counters[key] = entry
+  return entry.count, nil
+}
+
+func (s *MemoryQuotaStore) Get(ctx context.Context, key string) (int64, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  entry, exist := s.counters[key]
+  if !exist || time.Now().After(entry.expiry) {
+    return 0, nil
+  }
+  return entry.count, nil
+}
+
+func (s *MemoryQuotaStore) Reset(ctx context.Context, key string) error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  delete(s.counters, key)
+  return nil
+}
+
+type quotaConfig struct {
+  principal func(r *http.Request) string
+  limits func(principal string) int64
+}
+
+type quotaOption func(cfg *quotaConfig)
+
+// Principal from the request instead of userv.Principal e.g. JWT subject
+func QuotaPrincipal(principal func(r *http.Request) string) quotaOption {
+  return func(cfg *quotaConfig) {
+    cfg.principal = principal
+  }
+}
+
+// Per principal allowance overriding the default limit
+func QuotaLimits(limits func(principal string) int64) quotaOption {
+  return func(cfg *quotaConfig) {
+    cfg.limits = limits
+  }
+}
+
+type Quota struct {
+  store QuotaStore
+  period QuotaPeriod
+  cfg *quotaConfig
+}
+
+func NewQuota(
+  store QuotaStore, period QuotaPeriod, limit int64, opts ...quotaOption,
+) *Quota {
+  cfg := &quotaConfig{
+    principal: func(r *http.Request) string {
+      principal, _ := Principal(r.Context())
+      return principal
+    },
+    limits: func(principal string) int64 { return limit },
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return &Quota{store: store, period: period, cfg: cfg}
+}
+
+func (q *Quota) key(principal string, start time.Time) string {
+  layout := time.DateOnly
+  if q.period == QuotaMonthly {
+    layout = "2006-01"
+  }
+  return fmt.Sprintf("%s|%s|%s", principal, q.period, start.Format(layout))
+}
+
+// Counts requests per principal, must follow the authentication middleware
+func (q *Quota) Enforce() Middleware {
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      principal := q.cfg.principal(r)
+      if len(principal) == 0 {
+        WriteError(w, Unautorized("quota principal is required"))
+        return
+      }
+      start, reset := q.period.window(time.Now())
+      used, err := q.store.Incr(r.Context(), q.key(principal, start), reset)
+      if err != nil {
+        WriteError(w, err)
+        return
+      }
+      limit := q.cfg.limits(principal)
+      w.Header().Set("X-Quota-Limit", strconv.FormatInt(limit, 10))
+      w.Header().Set(
+        "X-Quota-Remaining", strconv.FormatInt(max(limit - used, 0), 10),
+      )
+      w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+      if used > limit {
+        w.Header().Set(
+          "Retry-After", strconv.Itoa(int(time.Until(reset).Seconds()) + 1),
+        )
+        WriteError(w, TooManyRequests(fmt.Sprintf(
+          "%s quota of %d requests exceeded", q.period, limit,
+        )))
+        return
+      }
+      next(w, r)
+    }
+  }
+}
+
+type resQuota struct {
+  Principal string `json:"principal"`
+  Period string `json:"period"`
+  Limit int64 `json:"limit"`
+  Used int64 `json:"used"`
+  Remaining int64 `json:"remaining"`
+  Reset time.Time `json:"reset"`
+}
+
+// GET inspects and DELETE resets the current window of ?principal=
+func (q *Quota) AdminHandler() http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    principal := r.URL.Query().Get("principal")
+    if len(principal) == 0 {
+      WriteError(w, BadRequest("principal query parameter is required"))
+      return
+    }
+    start, reset := q.period.window(time.Now())
+    key := q.key(principal, start)
+    switch r.Method {
+    case http.MethodGet:
+      used, err := q.store.Get(r.Context(), key)
+      if err != nil {
+        WriteError(w, err)
+        return
+      }
+      limit := q.cfg.limits(principal)
+      WriteResponse(w, http.StatusOK, resQuota{
+        Principal: principal, Period: q.period.String(), Limit: limit,
+        Used: used, Remaining: max(limit - used, 0), Reset: reset,
+      })
+    case http.MethodDelete:
+      err := q.store.Reset(r.Context(), key)
+      if err != nil {
+        WriteError(w, err)
+        return
+      }
+      w.WriteHeader(http.StatusNoContent)
+    default:
+      w.Header().Set("Allow", "GET, DELETE")
+      WriteError(w, MethodNotAllowed("method not allowed"))
+    }
+  }
+}
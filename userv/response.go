@@ -0,0 +1,54 @@
+package userv
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type responseConfig struct {
+  header http.Header
+  contentType string
+}
+
+type responseOption func(cfg *responseConfig)
+
+func Header(key, value string) responseOption {
+  return func(cfg *responseConfig) {
+    cfg.header.Add(key, value)
+  }
+}
+
+// max-age=<seconds>, zero or negative maxAge disables caching with no-store
+func CacheControl(maxAge time.Duration) responseOption {
+  return func(cfg *responseConfig) {
+    if maxAge <= 0 {
+      cfg.header.Set("Cache-Control", "no-store")
+      return
+    }
+    cfg.header.Set(
+      "Cache-Control", fmt.Sprintf("max-age=%d", int(maxAge.Seconds())),
+    )
+  }
+}
+
+// e.g. 201 Created with the URL of the new resource
+func Location(url string) responseOption {
+  return func(cfg *responseConfig) {
+    cfg.header.Set("Location", url)
+  }
+}
+
+// Overrides the negotiated content type, []byte and string responses are
+// written as is, other values are encoded with the encoder registered for the
+// content type
+func ContentType(contentType string) responseOption {
+  return func(cfg *responseConfig) {
+    cfg.contentType = contentType
+  }
+}
+
+func bodyAllowed(statusCode int) bool {
+  return statusCode >= 200 && statusCode != http.StatusNoContent &&
+    statusCode != http.StatusNotModified
+}
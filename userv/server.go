@@ -0,0 +1,107 @@
+package userv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type serverConfig struct {
+  lifecycle *Lifecycle
+  timeout time.Duration
+  h2c bool
+  listeners []listenFunc
+}
+
+type serverOption func(cfg *serverConfig)
+
+func ServerLifecycle(lifecycle *Lifecycle) serverOption {
+  return func(cfg *serverConfig) {
+    cfg.lifecycle = lifecycle
+  }
+}
+
+func ServerShutdownTimeout(timeout time.Duration) serverOption {
+  return func(cfg *serverConfig) {
+    cfg.timeout = timeout
+  }
+}
+
+// Serves unencrypted HTTP/2 alongside HTTP/1 for internal traffic
+func ServerH2C() serverOption {
+  return func(cfg *serverConfig) {
+    cfg.h2c = true
+  }
+}
+
+// Serves until ctx is canceled or SIGINT/SIGTERM is received, then drains
+// in-flight requests and stops lifecycle tasks in reverse order
+func ListenAndServe(
+  ctx context.Context, srv *http.Server, opts ...serverOption,
+) error {
+  return serve(ctx, srv, srv.ListenAndServe, srv.Serve, opts...)
+}
+
+func serve(
+  ctx context.Context, srv *http.Server, listen func() error,
+  serveOn func(l net.Listener) error, opts ...serverOption,
+) error {
+  cfg := &serverConfig{
+    lifecycle: defaultLifecycle,
+    timeout: 20 * time.Second,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  if cfg.h2c {
+    var protocols http.Protocols
+    protocols.SetHTTP1(true)
+    protocols.SetHTTP2(true)
+    protocols.SetUnencryptedHTTP2(true)
+    srv.Protocols = &protocols
+  }
+  if len(cfg.listeners) > 0 {
+    listeners, err := openListeners(cfg.listeners)
+    if err != nil {
+      return err
+    }
+    listen = func() error {
+      srvErr := make(chan error, len(listeners))
+      for _, l := range listeners {
+        go func() {
+          srvErr <- serveOn(l)
+        }()
+      }
+      return <-srvErr
+    }
+  }
+  ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+  defer stop()
+  cfg.lifecycle.Start(ctx)
+  srvErr := make(chan error, 1)
+  go func() {
+    srvErr <- listen()
+  }()
+  var err error
+  select {
+  case err = <-srvErr:
+  case <-ctx.Done():
+  }
+  if errors.Is(err, http.ErrServerClosed) {
+    err = nil
+  }
+  shutdownCtx, cancel := context.WithTimeout(
+    context.WithoutCancel(ctx), cfg.timeout,
+  )
+  defer cancel()
+  start := time.Now()
+  shutdownErr := srv.Shutdown(shutdownCtx)
+  LogAction("shutdown", shutdownErr, start, "http server")
+  lifecycleErr := cfg.lifecycle.Stop(context.WithoutCancel(ctx))
+  return errors.Join(err, shutdownErr, lifecycleErr)
+}
@@ -0,0 +1,306 @@
+package userv
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Session struct {
+  ID string `json:"id"`
+  Values map[string]any `json:"values"`
+  Created time.Time `json:"created"`
+  LastSeen time.Time `json:"lastSeen"`
+}
+
+// Load returns nil, nil for a missing or expired session
+type SessionStore interface {
+  Load(ctx context.Context, id string) (*Session, error)
+  Save(ctx context.Context, sess *Session, ttl time.Duration) error
+  Delete(ctx context.Context, id string) error
+}
+
+type memoryEntry struct {
+  sess Session
+  expiry time.Time
+}
+
+type MemorySessionStore struct {
+  mtx sync.Mutex
+  sessions map[string]memoryEntry
+  evictor Evictor
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+  return &MemorySessionStore{sessions: make(map[string]memoryEntry)}
+}
+
+func (s *MemorySessionStore) Load(
+  ctx context.Context, id string,
+) (*Session, error) {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  entry, exist := s.sessions[id]
+  if !exist {
+    return nil, nil
+  }
+  if time.Now().After(entry.expiry) {
+    delete(s.sessions, id)
+    return nil, nil
+  }
+  sess := entry.sess
+  sess.Values = maps.Clone(entry.sess.Values)
+  return &sess, nil
+}
+
+func (s *MemorySessionStore) Save(
+  ctx context.Context, sess *Session, ttl time.Duration,
+) error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  EvictExpired(&s.evictor, s.sessions, func(entry memoryEntry) time.Time {
+    return entry.expiry
+  })
+  s.sessions[sess.ID] = memoryEntry{sess: *sess, expiry: time.Now().Add(ttl)}
+  return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+  s.mtx.Lock()
+  defer s.mtx.Unlock()
+  delete(s.sessions, id)
+  return nil
+}
+
+type sessionConfig struct {
+  cookie string
+  idle time.Duration
+  absolute time.Duration
+  sameSite http.SameSite
+}
+
+type sessionOption func(cfg *sessionConfig)
+
+func SessionCookie(name string) sessionOption {
+  return func(cfg *sessionConfig) {
+    cfg.cookie = name
+  }
+}
+
+func SessionIdleTimeout(idle time.Duration) sessionOption {
+  return func(cfg *sessionConfig) {
+    cfg.idle = idle
+  }
+}
+
+func SessionAbsoluteTimeout(absolute time.Duration) sessionOption {
+  return func(cfg *sessionConfig) {
+    cfg.absolute = absolute
+  }
+}
+
+func SessionSameSite(sameSite http.SameSite) sessionOption {
+  return func(cfg *sessionConfig) {
+    cfg.sameSite = sameSite
+  }
+}
+
+type SessionManager struct {
+  store SessionStore
+  aead cipher.AEAD
+  cfg *sessionConfig
+}
+
+// The key must be 32 bytes, the session ID is sealed with AES-256-GCM
+func NewSessionManager(
+  store SessionStore, key []byte, opts ...sessionOption,
+) (*SessionManager, error) {
+  if len(key) != 32 {
+    return nil, errors.New("session key must be 32 bytes")
+  }
+  block, err := aes.NewCipher(key)
+  if err != nil {
+    return nil, err
+  }
+  aead, err := cipher.NewGCM(block)
+  if err != nil {
+    return nil, err
+  }
+  cfg := &sessionConfig{
+    cookie: "session",
+    idle: 30 * time.Minute,
+    absolute: 12 * time.Hour,
+    sameSite: http.SameSiteLaxMode,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return &SessionManager{store: store, aead: aead, cfg: cfg}, nil
+}
+
+func (m *SessionManager) seal(id string) string {
+  nonce := make([]byte, m.aead.NonceSize())
+  _, _ = rand.Read(nonce)
+  sealed := m.aead.Seal(nonce, nonce, []byte(id), []byte(m.cfg.cookie))
+  return base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+func (m *SessionManager) open(value string) (string, bool) {
+  sealed, err := base64.RawURLEncoding.DecodeString(value)
+  if err != nil || len(sealed) < m.aead.NonceSize() {
+    return "", false
+  }
+  nonce, cipherText := sealed[:m.aead.NonceSize()], sealed[m.aead.NonceSize():]
+  id, err := m.aead.Open(nil, nonce, cipherText, []byte(m.cfg.cookie))
+  if err != nil {
+    return "", false
+  }
+  return string(id), true
+}
+
+func (m *SessionManager) setCookie(w http.ResponseWriter, sess *Session) {
+  http.SetCookie(w, &http.Cookie{
+    Name: m.cfg.cookie,
+    Value: m.seal(sess.ID),
+    Path: "/",
+    HttpOnly: true,
+    Secure: true,
+    SameSite: m.cfg.sameSite,
+    Expires: sess.Created.Add(m.cfg.absolute),
+  })
+}
+
+func (m *SessionManager) clearCookie(w http.ResponseWriter) {
+  http.SetCookie(w, &http.Cookie{
+    Name: m.cfg.cookie,
+    Value: "",
+    Path: "/",
+    HttpOnly: true,
+    Secure: true,
+    SameSite: m.cfg.sameSite,
+    Expires: time.Unix(0, 0),
+    MaxAge: -1,
+  })
+}
+
+func (m *SessionManager) ttl(sess *Session) time.Duration {
+  absolute := time.Until(sess.Created.Add(m.cfg.absolute))
+  return min(m.cfg.idle, absolute)
+}
+
+func (m *SessionManager) expired(sess *Session) bool {
+  now := time.Now()
+  return now.After(sess.LastSeen.Add(m.cfg.idle)) ||
+    now.After(sess.Created.Add(m.cfg.absolute))
+}
+
+type sessionHolder struct {
+  sess *Session
+}
+
+type sessionKey struct{}
+
+func SessionFrom(ctx context.Context) (*Session, bool) {
+  holder, exist := ctx.Value(sessionKey{}).(*sessionHolder)
+  if !exist || holder.sess == nil {
+    return nil, false
+  }
+  return holder.sess, true
+}
+
+func (m *SessionManager) load(r *http.Request) (*Session, error) {
+  cookie, err := r.Cookie(m.cfg.cookie)
+  if err != nil {
+    return nil, nil
+  }
+  id, valid := m.open(cookie.Value)
+  if !valid {
+    return nil, nil
+  }
+  sess, err := m.store.Load(r.Context(), id)
+  if err != nil || sess == nil {
+    return nil, err
+  }
+  if m.expired(sess) {
+    return nil, m.store.Delete(r.Context(), id)
+  }
+  return sess, nil
+}
+
+// Loads the session into the context and persists it after the handler
+func (m *SessionManager) Sessions() func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      sess, err := m.load(r)
+      if err != nil {
+        WriteError(w, ServiceUnavailable(err.Error()))
+        return
+      }
+      holder := &sessionHolder{sess: sess}
+      ctx := context.WithValue(r.Context(), sessionKey{}, holder)
+      next.ServeHTTP(w, r.WithContext(ctx))
+      if holder.sess != nil {
+        holder.sess.LastSeen = time.Now().UTC()
+        _ = m.store.Save(ctx, holder.sess, m.ttl(holder.sess))
+      }
+    })
+  }
+}
+
+func newSessionID() string {
+  rnd := make([]byte, 32)
+  _, _ = rand.Read(rnd)
+  return base64.RawURLEncoding.EncodeToString(rnd)
+}
+
+// Call on login: issues a fresh session ID keeping existing values
+func (m *SessionManager) Regenerate(
+  w http.ResponseWriter, r *http.Request,
+) (*Session, error) {
+  holder, exist := r.Context().Value(sessionKey{}).(*sessionHolder)
+  if !exist {
+    return nil, errors.New("session middleware is not installed")
+  }
+  now := time.Now().UTC()
+  sess := &Session{
+    ID: newSessionID(),
+    Values: make(map[string]any),
+    Created: now,
+    LastSeen: now,
+  }
+  if holder.sess != nil {
+    sess.Values = holder.sess.Values
+    err := m.store.Delete(r.Context(), holder.sess.ID)
+    if err != nil {
+      return nil, err
+    }
+  }
+  err := m.store.Save(r.Context(), sess, m.ttl(sess))
+  if err != nil {
+    return nil, err
+  }
+  holder.sess = sess
+  m.setCookie(w, sess)
+  return sess, nil
+}
+
+func (m *SessionManager) Destroy(w http.ResponseWriter, r *http.Request) error {
+  holder, exist := r.Context().Value(sessionKey{}).(*sessionHolder)
+  if !exist {
+    return errors.New("session middleware is not installed")
+  }
+  m.clearCookie(w)
+  if holder.sess == nil {
+    return nil
+  }
+  id := holder.sess.ID
+  holder.sess = nil
+  return m.store.Delete(r.Context(), id)
+}
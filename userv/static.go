@@ -0,0 +1,168 @@
+package userv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+type staticConfig struct {
+  fsys fs.FS
+  maxAge time.Duration
+  index string
+  fallback bool
+}
+
+type staticOption func(cfg *staticConfig)
+
+func StaticFS(fsys fs.FS) staticOption { // e.g. embed.FS
+  return func(cfg *staticConfig) {
+    cfg.fsys = fsys
+  }
+}
+
+func StaticMaxAge(maxAge time.Duration) staticOption {
+  return func(cfg *staticConfig) {
+    cfg.maxAge = maxAge
+  }
+}
+
+func StaticIndex(index string) staticOption {
+  return func(cfg *staticConfig) {
+    cfg.index = index
+  }
+}
+
+var precompressed = []struct{
+  encoding string
+  ext string
+}{
+  {"br", ".br"},
+  {"gzip", ".gz"},
+}
+
+func openFile(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+  file, err := fsys.Open(name)
+  if err != nil {
+    return nil, nil, err
+  }
+  info, err := file.Stat()
+  if err != nil {
+    _ = file.Close()
+    return nil, nil, err
+  }
+  return file, info, nil
+}
+
+func serveFile(
+  w http.ResponseWriter, r *http.Request, cfg *staticConfig,
+  name, cacheControl string,
+) error {
+  file, info, err := openFile(cfg.fsys, name)
+  if err != nil {
+    return err
+  }
+  if info.IsDir() {
+    _ = file.Close()
+    return serveFile(w, r, cfg, path.Join(name, cfg.index), cacheControl)
+  }
+  // Pre-compressed variant
+  accept := r.Header.Get("Accept-Encoding")
+  for _, pc := range precompressed {
+    if !strings.Contains(accept, pc.encoding) {
+      continue
+    }
+    cfile, cinfo, err := openFile(cfg.fsys, name + pc.ext)
+    if err != nil {
+      continue
+    }
+    _ = file.Close()
+    file, info = cfile, cinfo
+    w.Header().Set("Content-Encoding", pc.encoding)
+    break
+  }
+  defer func() {
+    _ = file.Close()
+  }()
+  w.Header().Add("Vary", "Accept-Encoding")
+  ctype := mime.TypeByExtension(path.Ext(name))
+  if len(ctype) > 0 {
+    w.Header().Set("Content-Type", ctype)
+  }
+  w.Header().Set("Cache-Control", cacheControl)
+  w.Header().Set(
+    "ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+  )
+  content, seekable := file.(io.ReadSeeker)
+  if !seekable {
+    buf, err := io.ReadAll(file)
+    if err != nil {
+      return err
+    }
+    content = bytes.NewReader(buf)
+  }
+  http.ServeContent(w, r, name, info.ModTime(), content)
+  return nil
+}
+
+func staticHandler(cfg *staticConfig) http.Handler {
+  cacheControl := "no-cache"
+  if cfg.maxAge > 0 {
+    cacheControl = fmt.Sprintf("public, max-age=%d", int(cfg.maxAge.Seconds()))
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    // Directory traversal protection
+    name := strings.TrimPrefix(path.Clean("/" + r.URL.Path), "/")
+    if len(name) == 0 {
+      name = "."
+    }
+    if !fs.ValidPath(name) {
+      WriteError(w, NotFound("not found"))
+      return
+    }
+    err := serveFile(w, r, cfg, name, cacheControl)
+    if errors.Is(err, fs.ErrNotExist) && cfg.fallback &&
+      len(path.Ext(name)) == 0 {
+      // Client-side route, index is never cached
+      err = serveFile(w, r, cfg, cfg.index, "no-cache")
+    }
+    if errors.Is(err, fs.ErrNotExist) {
+      WriteError(w, NotFound("not found"))
+      return
+    }
+    if err != nil {
+      WriteError(w, InternalServerError(err.Error()))
+    }
+  })
+}
+
+func Static(dir string, opts ...staticOption) http.Handler {
+  cfg := &staticConfig{
+    fsys: os.DirFS(dir),
+    index: "index.html",
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return staticHandler(cfg)
+}
+
+func SPA(dir string, indexFallback string, opts ...staticOption) http.Handler {
+  cfg := &staticConfig{
+    fsys: os.DirFS(dir),
+    index: indexFallback,
+    fallback: true,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return staticHandler(cfg)
+}
@@ -0,0 +1,109 @@
+package userv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type timings struct {
+  mtx sync.Mutex
+  start time.Time
+  names []string
+  spans map[string]time.Duration
+}
+
+type timingsKey struct{}
+
+func withTimings(r *http.Request) (*http.Request, *timings) {
+  tms, exist := r.Context().Value(timingsKey{}).(*timings)
+  if exist {
+    return r, tms
+  }
+  tms = &timings{start: time.Now(), spans: make(map[string]time.Duration)}
+  ctx := context.WithValue(r.Context(), timingsKey{}, tms)
+  return r.WithContext(ctx), tms
+}
+
+// defer userv.Span(ctx, "db")(), spans with the same name are summed
+func Span(ctx context.Context, name string) func() {
+  tms, exist := ctx.Value(timingsKey{}).(*timings)
+  if !exist {
+    return func() {}
+  }
+  start := time.Now()
+  return func() {
+    elapsed := time.Since(start)
+    tms.mtx.Lock()
+    defer tms.mtx.Unlock()
+    _, exist := tms.spans[name]
+    if !exist {
+      tms.names = append(tms.names, name)
+    }
+    tms.spans[name] += elapsed
+  }
+}
+
+func (t *timings) millis() map[string]int {
+  t.mtx.Lock()
+  defer t.mtx.Unlock()
+  if len(t.spans) == 0 {
+    return nil
+  }
+  ms := make(map[string]int, len(t.spans))
+  for name, dur := range t.spans {
+    ms[name] = int(dur.Milliseconds())
+  }
+  return ms
+}
+
+func (t *timings) header() string {
+  t.mtx.Lock()
+  defer t.mtx.Unlock()
+  metrics := make([]string, 0, len(t.names) + 1)
+  for _, name := range t.names {
+    ms := float64(t.spans[name].Microseconds()) / 1000
+    metrics = append(metrics, fmt.Sprintf("%s;dur=%.1f", name, ms))
+  }
+  total := float64(time.Since(t.start).Microseconds()) / 1000
+  metrics = append(metrics, fmt.Sprintf("total;dur=%.1f", total))
+  return strings.Join(metrics, ", ")
+}
+
+type timingWriter struct {
+  http.ResponseWriter
+  tms *timings
+  wroteHeader bool
+}
+
+func (t *timingWriter) Unwrap() http.ResponseWriter {
+  return t.ResponseWriter
+}
+
+func (t *timingWriter) WriteHeader(statusCode int) {
+  if !t.wroteHeader {
+    t.wroteHeader = true
+    t.Header().Set("Server-Timing", t.tms.header())
+  }
+  t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *timingWriter) Write(body []byte) (int, error) {
+  if !t.wroteHeader {
+    t.WriteHeader(http.StatusOK)
+  }
+  return t.ResponseWriter.Write(body)
+}
+
+// Emits spans recorded before the response header as Server-Timing
+func ServerTiming() func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      r, tms := withTimings(r)
+      next.ServeHTTP(&timingWriter{ResponseWriter: w, tms: tms}, r)
+    })
+  }
+}
@@ -0,0 +1,169 @@
+package userv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+)
+
+type tlsConfig struct {
+  certFile string
+  keyFile string
+  getCertificate func(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+  acme bool
+  clientCAs *x509.CertPool
+  clientOptional bool
+}
+
+type tlsOption func(cfg *tlsConfig)
+
+func TLSCertFile(certFile, keyFile string) tlsOption {
+  return func(cfg *tlsConfig) {
+    cfg.certFile = certFile
+    cfg.keyFile = keyFile
+  }
+}
+
+// ACME certificates e.g. TLSAutocert(autocertManager.GetCertificate) with the
+// tls-alpn-01 challenge enabled
+func TLSAutocert(
+  getCertificate func(hello *tls.ClientHelloInfo) (*tls.Certificate, error),
+) tlsOption {
+  return func(cfg *tlsConfig) {
+    cfg.getCertificate = getCertificate
+    cfg.acme = true
+  }
+}
+
+// Mutual TLS: client certificates are required and verified against the pool
+func TLSClientCAs(pool *x509.CertPool) tlsOption {
+  return func(cfg *tlsConfig) {
+    cfg.clientCAs = pool
+  }
+}
+
+// Client certificates are verified only when presented
+func TLSClientCertOptional() tlsOption {
+  return func(cfg *tlsConfig) {
+    cfg.clientOptional = true
+  }
+}
+
+func ReadCertPool(files ...string) (*x509.CertPool, error) {
+  pool := x509.NewCertPool()
+  for _, file := range files {
+    pem, err := os.ReadFile(file)
+    if err != nil {
+      return nil, err
+    }
+    if !pool.AppendCertsFromPEM(pem) {
+      return nil, errors.New("no certificates in " + file)
+    }
+  }
+  return pool, nil
+}
+
+// TLS 1.2+ with AEAD ECDHE cipher suites and HTTP/2
+func TLSConfig(opts ...tlsOption) (*tls.Config, error) {
+  cfg := &tlsConfig{}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  tlsCfg := &tls.Config{
+    MinVersion: tls.VersionTLS12,
+    CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+    CipherSuites: []uint16{
+      tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+      tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+      tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+      tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+      tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+      tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+    },
+    NextProtos: []string{"h2", "http/1.1"},
+    GetCertificate: cfg.getCertificate,
+  }
+  if cfg.acme {
+    tlsCfg.NextProtos = append(tlsCfg.NextProtos, "acme-tls/1")
+  }
+  if len(cfg.certFile) > 0 {
+    cert, err := tls.LoadX509KeyPair(cfg.certFile, cfg.keyFile)
+    if err != nil {
+      return nil, err
+    }
+    tlsCfg.Certificates = []tls.Certificate{cert}
+  }
+  if cfg.getCertificate == nil && len(tlsCfg.Certificates) == 0 {
+    return nil, errors.New("TLS certificate is not configured")
+  }
+  if cfg.clientCAs != nil {
+    tlsCfg.ClientCAs = cfg.clientCAs
+    tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+    if cfg.clientOptional {
+      tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+    }
+  }
+  return tlsCfg, nil
+}
+
+// ListenAndServe over TLS, certificates come from tlsCfg
+func ListenAndServeTLS(
+  ctx context.Context, srv *http.Server, tlsCfg *tls.Config,
+  opts ...serverOption,
+) error {
+  srv.TLSConfig = tlsCfg
+  listen := func() error {
+    return srv.ListenAndServeTLS("", "")
+  }
+  serveOn := func(l net.Listener) error {
+    return srv.ServeTLS(l, "", "")
+  }
+  return serve(ctx, srv, listen, serveOn, opts...)
+}
+
+type clientCertKey struct{}
+
+// Verified mTLS client certificate, set by ClientCerts
+func ClientCert(ctx context.Context) (*x509.Certificate, bool) {
+  cert, exist := ctx.Value(clientCertKey{}).(*x509.Certificate)
+  return cert, exist
+}
+
+// Exposes the verified client certificate to handlers via ClientCert
+func ClientCerts() func(next http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 &&
+        len(r.TLS.VerifiedChains[0]) > 0 {
+        cert := r.TLS.VerifiedChains[0][0]
+        ctx := context.WithValue(r.Context(), clientCertKey{}, cert)
+        r = r.WithContext(ctx)
+      }
+      next.ServeHTTP(w, r)
+    })
+  }
+}
+
+// Restricts the route to client certificates with one of the common names
+func RequireClientCert(commonNames ...string) Middleware {
+  return func(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+      cert, exist := ClientCert(r.Context())
+      if !exist {
+        WriteError(w, Unautorized("client certificate is required"))
+        return
+      }
+      if len(commonNames) > 0 &&
+        !slices.Contains(commonNames, cert.Subject.CommonName) {
+        WriteError(w, Forbidden("client certificate is not allowed"))
+        return
+      }
+      next(w, r)
+    }
+  }
+}
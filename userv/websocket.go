@@ -0,0 +1,417 @@
+package userv
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+  WSText = 1
+  WSBinary = 2
+  wsClose = 8
+  wsPing = 9
+  wsPong = 10
+)
+
+const (
+  WSCloseNormal = 1000
+  WSCloseGoingAway = 1001
+  WSCloseProtocolError = 1002
+  WSClosePolicy = 1008
+  WSCloseTooBig = 1009
+)
+
+var ErrWSQueueFull = errors.New("websocket send queue is full")
+var ErrWSClosed = errors.New("websocket connection is closed")
+
+type wsConfig struct {
+  origins []string
+  queue int
+  pingInterval time.Duration
+  pongWait time.Duration
+  writeTimeout time.Duration
+  maxMessage int64
+}
+
+type wsOption func(cfg *wsConfig)
+
+// Allowed Origin header values, same-host origins are always allowed
+func WSOrigins(origins ...string) wsOption {
+  return func(cfg *wsConfig) {
+    cfg.origins = origins
+  }
+}
+
+func WSSendQueue(size int) wsOption {
+  return func(cfg *wsConfig) {
+    cfg.queue = size
+  }
+}
+
+func WSKeepAlive(pingInterval, pongWait time.Duration) wsOption {
+  return func(cfg *wsConfig) {
+    cfg.pingInterval = pingInterval
+    cfg.pongWait = pongWait
+  }
+}
+
+func WSMaxMessage(size int64) wsOption {
+  return func(cfg *wsConfig) {
+    cfg.maxMessage = size
+  }
+}
+
+func newWSConfig(opts ...wsOption) *wsConfig {
+  cfg := &wsConfig{
+    queue: 64,
+    pingInterval: 30 * time.Second,
+    pongWait: 60 * time.Second,
+    writeTimeout: 10 * time.Second,
+    maxMessage: 1 << 20,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return cfg
+}
+
+type wsMessage struct {
+  opcode byte
+  data []byte
+}
+
+type WSConn struct {
+  conn net.Conn
+  br *bufio.Reader
+  cfg *wsConfig
+  writeMtx sync.Mutex
+  send chan wsMessage
+  done chan struct{}
+  closeOnce sync.Once
+  Request *http.Request
+}
+
+func headerContains(h http.Header, name, token string) bool {
+  for value := range strings.SplitSeq(h.Get(name), ",") {
+    if strings.EqualFold(strings.TrimSpace(value), token) {
+      return true
+    }
+  }
+  return false
+}
+
+func wsOriginAllowed(r *http.Request, origins []string) bool {
+  origin := r.Header.Get("Origin")
+  if len(origin) == 0 || slices.Contains(origins, origin) {
+    return true
+  }
+  host := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+  return strings.EqualFold(host, r.Host)
+}
+
+func wsAccept(key string) string {
+  h := sha1.New()
+  h.Write([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+  return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func upgrade(
+  w http.ResponseWriter, r *http.Request, cfg *wsConfig,
+) (*WSConn, error) {
+  key := r.Header.Get("Sec-WebSocket-Key")
+  if r.Method != http.MethodGet ||
+    !headerContains(r.Header, "Connection", "upgrade") ||
+    !headerContains(r.Header, "Upgrade", "websocket") ||
+    r.Header.Get("Sec-WebSocket-Version") != "13" || len(key) == 0 {
+    return nil, BadRequest("invalid websocket upgrade request")
+  }
+  if !wsOriginAllowed(r, cfg.origins) {
+    return nil, Forbidden("websocket origin is not allowed")
+  }
+  conn, brw, err := http.NewResponseController(w).Hijack()
+  if err != nil {
+    return nil, InternalServerError(err.Error())
+  }
+  _, err = fmt.Fprintf(
+    brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n" +
+      "Connection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAccept(key),
+  )
+  if err == nil {
+    err = brw.Flush()
+  }
+  if err != nil {
+    _ = conn.Close()
+    return nil, err
+  }
+  c := &WSConn{
+    conn: conn,
+    br: brw.Reader,
+    cfg: cfg,
+    send: make(chan wsMessage, cfg.queue),
+    done: make(chan struct{}),
+    Request: r,
+  }
+  _ = conn.SetDeadline(time.Time{})
+  go c.writeLoop()
+  return c, nil
+}
+
+// Hijacks the connection, writes are queued and sent with ping keepalive
+func Upgrade(
+  w http.ResponseWriter, r *http.Request, opts ...wsOption,
+) (*WSConn, error) {
+  return upgrade(w, r, newWSConfig(opts...))
+}
+
+func (c *WSConn) writeFrame(opcode byte, data []byte) error {
+  c.writeMtx.Lock()
+  defer c.writeMtx.Unlock()
+  head := make([]byte, 2, 10)
+  head[0] = 0x80 | opcode
+  switch l := len(data); {
+  case l < 126:
+    head[1] = byte(l)
+  case l <= 0xffff:
+    head[1] = 126
+    head = binary.BigEndian.AppendUint16(head, uint16(l))
+  default:
+    head[1] = 127
+    head = binary.BigEndian.AppendUint64(head, uint64(l))
+  }
+  _ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.writeTimeout))
+  _, err := c.conn.Write(append(head, data...))
+  return err
+}
+
+func (c *WSConn) writeLoop() {
+  ticker := time.NewTicker(c.cfg.pingInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case msg := <-c.send:
+      err := c.writeFrame(msg.opcode, msg.data)
+      if err != nil {
+        c.shutdown()
+        return
+      }
+    case <-ticker.C:
+      err := c.writeFrame(wsPing, nil)
+      if err != nil {
+        c.shutdown()
+        return
+      }
+    case <-c.done:
+      return
+    }
+  }
+}
+
+func (c *WSConn) readFrame() (bool, byte, []byte, error) {
+  head := make([]byte, 2)
+  _, err := io.ReadFull(c.br, head)
+  if err != nil {
+    return false, 0, nil, err
+  }
+  fin, opcode := head[0] & 0x80 != 0, head[0] & 0x0f
+  masked, length := head[1] & 0x80 != 0, uint64(head[1] & 0x7f)
+  if !masked {
+    return false, 0, nil, errors.New("websocket: unmasked client frame")
+  }
+  switch length {
+  case 126:
+    ext := make([]byte, 2)
+    _, err = io.ReadFull(c.br, ext)
+    length = uint64(binary.BigEndian.Uint16(ext))
+  case 127:
+    ext := make([]byte, 8)
+    _, err = io.ReadFull(c.br, ext)
+    length = binary.BigEndian.Uint64(ext)
+  }
+  if err != nil {
+    return false, 0, nil, err
+  }
+  if length > uint64(c.cfg.maxMessage) {
+    return false, 0, nil, errWSTooBig
+  }
+  mask := make([]byte, 4)
+  _, err = io.ReadFull(c.br, mask)
+  if err != nil {
+    return false, 0, nil, err
+  }
+  data := make([]byte, length)
+  _, err = io.ReadFull(c.br, data)
+  if err != nil {
+    return false, 0, nil, err
+  }
+  for i := range data {
+    data[i] ^= mask[i % 4]
+  }
+  return fin, opcode, data, nil
+}
+
+var errWSTooBig = errors.New("websocket: message too big")
+
+// Returns WSText or WSBinary messages, control frames are handled internally
+func (c *WSConn) ReadMessage() (int, []byte, error) {
+  var opcode byte
+  var message []byte
+  for {
+    _ = c.conn.SetReadDeadline(time.Now().Add(c.cfg.pongWait))
+    fin, op, data, err := c.readFrame()
+    if err != nil {
+      code := WSCloseProtocolError
+      if errors.Is(err, errWSTooBig) {
+        code = WSCloseTooBig
+      }
+      _ = c.Close(code, err.Error())
+      return 0, nil, err
+    }
+    switch op {
+    case wsPing:
+      _ = c.writeFrame(wsPong, data)
+      continue
+    case wsPong:
+      continue
+    case wsClose:
+      _ = c.Close(WSCloseNormal, "")
+      return 0, nil, io.EOF
+    case WSText, WSBinary:
+      opcode, message = op, data
+    case 0: // continuation
+      message = append(message, data...)
+    }
+    if int64(len(message)) > c.cfg.maxMessage {
+      _ = c.Close(WSCloseTooBig, errWSTooBig.Error())
+      return 0, nil, errWSTooBig
+    }
+    if fin {
+      return int(opcode), message, nil
+    }
+  }
+}
+
+// Never blocks: fails with ErrWSQueueFull when the client does not keep up
+func (c *WSConn) Send(opcode int, data []byte) error {
+  select {
+  case <-c.done:
+    return ErrWSClosed
+  default:
+  }
+  select {
+  case c.send <- wsMessage{opcode: byte(opcode), data: data}:
+    return nil
+  default:
+    return ErrWSQueueFull
+  }
+}
+
+func (c *WSConn) shutdown() {
+  c.closeOnce.Do(func() {
+    close(c.done)
+    _ = c.conn.Close()
+  })
+}
+
+func (c *WSConn) Done() <-chan struct{} {
+  return c.done
+}
+
+func (c *WSConn) Close(code int, reason string) error {
+  select {
+  case <-c.done:
+    return nil
+  default:
+  }
+  payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+  payload = append(payload, reason...)
+  err := c.writeFrame(wsClose, payload)
+  c.shutdown()
+  return err
+}
+
+type Hub struct {
+  mtx sync.RWMutex
+  conns map[*WSConn]struct{}
+  cfg *wsConfig
+}
+
+func NewHub(opts ...wsOption) *Hub {
+  return &Hub{conns: make(map[*WSConn]struct{}), cfg: newWSConfig(opts...)}
+}
+
+func (h *Hub) Len() int {
+  h.mtx.RLock()
+  defer h.mtx.RUnlock()
+  return len(h.conns)
+}
+
+// Slow clients with a full send queue are disconnected
+func (h *Hub) Broadcast(opcode int, data []byte) {
+  h.mtx.RLock()
+  conns := make([]*WSConn, 0, len(h.conns))
+  for c := range h.conns {
+    conns = append(conns, c)
+  }
+  h.mtx.RUnlock()
+  for _, c := range conns {
+    err := c.Send(opcode, data)
+    if errors.Is(err, ErrWSQueueFull) {
+      _ = c.Close(WSClosePolicy, "send queue overflow")
+    }
+  }
+}
+
+// Upgrades, registers the connection and calls onMessage for every message
+func (h *Hub) Handler(
+  onMessage func(c *WSConn, opcode int, data []byte),
+) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    c, err := upgrade(w, r, h.cfg)
+    if err != nil {
+      WriteError(w, err)
+      return
+    }
+    h.mtx.Lock()
+    h.conns[c] = struct{}{}
+    h.mtx.Unlock()
+    defer func() {
+      h.mtx.Lock()
+      delete(h.conns, c)
+      h.mtx.Unlock()
+      c.shutdown()
+    }()
+    for {
+      opcode, data, err := c.ReadMessage()
+      if err != nil {
+        return
+      }
+      onMessage(c, opcode, data)
+    }
+  }
+}
+
+// Sends going away close frames, suitable for Lifecycle.OnShutdown
+func (h *Hub) Close(ctx context.Context) error {
+  h.mtx.RLock()
+  conns := make([]*WSConn, 0, len(h.conns))
+  for c := range h.conns {
+    conns = append(conns, c)
+  }
+  h.mtx.RUnlock()
+  for _, c := range conns {
+    _ = c.Close(WSCloseGoingAway, "server shutdown")
+  }
+  return ctx.Err()
+}
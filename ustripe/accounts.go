@@ -0,0 +1,99 @@
+package ustripe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+type tenantKey struct{}
+
+// Selects the Stripe account e.g. the legal entity country of the request
+func WithTenant(ctx context.Context, tenant string) context.Context {
+  return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func Tenant(ctx context.Context) (string, bool) {
+  tenant, exist := ctx.Value(tenantKey{}).(string)
+  return tenant, exist
+}
+
+type connectedAccountKey struct{}
+
+// Connect account sent as the Stripe-Account header via ConnectParams
+func WithConnectedAccount(ctx context.Context, account string) context.Context {
+  return context.WithValue(ctx, connectedAccountKey{}, account)
+}
+
+func ConnectedAccount(ctx context.Context) (string, bool) {
+  account, exist := ctx.Value(connectedAccountKey{}).(string)
+  return account, exist
+}
+
+// Sets the Stripe-Account header from the context connected account, if any
+func ConnectParams[P interface{ SetStripeAccount(val string) }](
+  ctx context.Context, params P,
+) P {
+  account, exist := ConnectedAccount(ctx)
+  if exist && len(account) > 0 {
+    params.SetStripeAccount(account)
+  }
+  return params
+}
+
+type Accounts struct {
+  mtx sync.RWMutex
+  clients map[string]*stripe.Client
+  fallback string
+}
+
+// keys maps tenants to Stripe secret keys
+func NewAccounts(keys map[string]string) (*Accounts, error) {
+  accts := &Accounts{clients: make(map[string]*stripe.Client, len(keys))}
+  for tenant, key := range keys {
+    err := accts.Add(tenant, key)
+    if err != nil {
+      return nil, err
+    }
+  }
+  return accts, nil
+}
+
+func (a *Accounts) Add(tenant, stripeKey string) error {
+  stp, err := NewClient(stripeKey)
+  if err != nil {
+    return err
+  }
+  a.mtx.Lock()
+  defer a.mtx.Unlock()
+  a.clients[tenant] = stp
+  return nil
+}
+
+// Tenant used when the context has no tenant
+func (a *Accounts) Fallback(tenant string) {
+  a.mtx.Lock()
+  defer a.mtx.Unlock()
+  a.fallback = tenant
+}
+
+func (a *Accounts) For(tenant string) (*stripe.Client, error) {
+  a.mtx.RLock()
+  defer a.mtx.RUnlock()
+  if len(tenant) == 0 {
+    tenant = a.fallback
+  }
+  stp, exist := a.clients[tenant]
+  if !exist {
+    return nil, fmt.Errorf("no Stripe account for tenant %q", tenant)
+  }
+  return stp, nil
+}
+
+// Client of the context tenant
+func (a *Accounts) Client(ctx context.Context) (*stripe.Client, error) {
+  tenant, _ := Tenant(ctx)
+  return a.For(tenant)
+}
@@ -0,0 +1,176 @@
+package ustripe
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+var (
+  zeroDecimal = []string{
+    "bif", "clp", "djf", "gnf", "jpy", "kmf", "krw", "mga", "pyg", "rwf",
+    "ugx", "vnd", "vuv", "xaf", "xof", "xpf",
+  }
+  threeDecimal = []string{"bhd", "jod", "kwd", "omr", "tnd"}
+)
+
+// Stripe minor unit exponent of the currency
+func CurrencyExponent(currency string) int {
+  currency = strings.ToLower(currency)
+  switch {
+  case slices.Contains(zeroDecimal, currency):
+    return 0
+  case slices.Contains(threeDecimal, currency):
+    return 3
+  default:
+    return 2
+  }
+}
+
+// Converts a decimal major unit amount e.g. 12.34 EUR to 1234, failing on more
+// decimals than the currency exponent e.g. 100.50 JPY
+func MinorUnits(amount, currency string) (int64, error) {
+  exp := CurrencyExponent(currency)
+  whole, frac, _ := strings.Cut(amount, ".")
+  if len(frac) > exp {
+    return 0, fmt.Errorf(
+      "%s %s: at most %d decimals", amount, strings.ToUpper(currency), exp,
+    )
+  }
+  frac += strings.Repeat("0", exp - len(frac))
+  minor, err := strconv.ParseInt(whole + frac, 10, 64)
+  if err != nil {
+    return 0, fmt.Errorf("%s %s: invalid amount", amount, currency)
+  }
+  return minor, nil
+}
+
+// Formats minor units as a decimal major unit amount e.g. 1234 EUR as 12.34
+// without a float64 round trip
+func MajorUnits(minor int64, currency string) string {
+  exp := CurrencyExponent(currency)
+  sign, abs := "", uint64(minor)
+  if minor < 0 {
+    // Two's complement negation keeps math.MinInt64 exact
+    sign, abs = "-", -abs
+  }
+  digits := strconv.FormatUint(abs, 10)
+  if exp == 0 {
+    return sign + digits
+  }
+  digits = strings.Repeat("0", max(0, exp + 1 - len(digits))) + digits
+  return sign + digits[:len(digits) - exp] + "." + digits[len(digits) - exp:]
+}
+
+type amountGuardConfig struct {
+  ceilings map[string]int64
+  defaultCeiling int64
+}
+
+type amountGuardOption func(cfg *amountGuardConfig)
+
+// Maximum amount in minor units of the currency
+func GuardCeiling(currency string, ceiling int64) amountGuardOption {
+  return func(cfg *amountGuardConfig) {
+    cfg.ceilings[strings.ToLower(currency)] = ceiling
+  }
+}
+
+// Stripe allows up to eight digits by default
+func GuardDefaultCeiling(ceiling int64) amountGuardOption {
+  return func(cfg *amountGuardConfig) {
+    cfg.defaultCeiling = ceiling
+  }
+}
+
+// Validates amounts before they reach Stripe calls
+type AmountGuard struct {
+  cfg *amountGuardConfig
+}
+
+func NewAmountGuard(opts ...amountGuardOption) *AmountGuard {
+  cfg := &amountGuardConfig{
+    ceilings: make(map[string]int64),
+    defaultCeiling: 99_999_999,
+  }
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return &AmountGuard{cfg: cfg}
+}
+
+// Positive, below the currency ceiling and a whole multiple of 10 for three
+// decimal currencies as Stripe requires
+func (g *AmountGuard) Check(amount int64, currency string) error {
+  currency = strings.ToLower(currency)
+  if len(currency) != 3 {
+    return fmt.Errorf("invalid currency %q", currency)
+  }
+  if amount <= 0 {
+    return fmt.Errorf("%d %s: amount must be positive", amount, currency)
+  }
+  ceiling, exist := g.cfg.ceilings[currency]
+  if !exist {
+    ceiling = g.cfg.defaultCeiling
+  }
+  if amount > ceiling {
+    return fmt.Errorf(
+      "%d %s: amount exceeds ceiling %d", amount, currency, ceiling,
+    )
+  }
+  if CurrencyExponent(currency) == 3 && amount % 10 != 0 {
+    return fmt.Errorf("%d %s: amount must end with 0", amount, currency)
+  }
+  return nil
+}
+
+func (g *AmountGuard) PaymentIntent(
+  params *stripe.PaymentIntentCreateParams,
+) error {
+  if params.Amount == nil || params.Currency == nil {
+    return errors.New("amount and currency are required")
+  }
+  return g.Check(*params.Amount, *params.Currency)
+}
+
+// Refunds must be in the charge currency and within the refundable amount
+func (g *AmountGuard) Refund(
+  params *stripe.RefundCreateParams, charge *stripe.Charge,
+) error {
+  currency := string(charge.Currency)
+  if params.Currency != nil && !strings.EqualFold(*params.Currency, currency) {
+    return fmt.Errorf(
+      "refund %s of %s charge: %w", *params.Currency, currency,
+      ErrCurrencyMismatch,
+    )
+  }
+  refundable := charge.Amount - charge.AmountRefunded
+  if params.Amount == nil {
+    return g.Check(refundable, currency)
+  }
+  if *params.Amount > refundable {
+    return fmt.Errorf(
+      "%d %s: refund exceeds refundable %d", *params.Amount, currency,
+      refundable,
+    )
+  }
+  return g.Check(*params.Amount, currency)
+}
+
+// Operations on amounts of different currencies e.g. transfers from charges
+func SameCurrency(currencies ...string) error {
+  for _, currency := range currencies[min(1, len(currencies)):] {
+    if !strings.EqualFold(currency, currencies[0]) {
+      return fmt.Errorf(
+        "%s and %s: %w", currencies[0], currency, ErrCurrencyMismatch,
+      )
+    }
+  }
+  return nil
+}
@@ -0,0 +1,162 @@
+package ustripe
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/urfave/cli/v3"
+)
+
+var balanceCSVHeader = []string{
+  "id", "created", "available_on", "type", "reporting_category", "source",
+  "description", "currency", "amount", "fee", "net", "status", "exchange_rate",
+}
+
+func balanceCSVRecord(bt *stripe.BalanceTransaction) []string {
+  var source string
+  if bt.Source != nil {
+    source = bt.Source.ID
+  }
+  var exchangeRate string
+  if bt.ExchangeRate != 0 {
+    exchangeRate = strconv.FormatFloat(bt.ExchangeRate, 'f', -1, 64)
+  }
+  return []string{
+    bt.ID,
+    time.Unix(bt.Created, 0).UTC().Format(time.RFC3339),
+    time.Unix(bt.AvailableOn, 0).UTC().Format(time.RFC3339),
+    string(bt.Type),
+    string(bt.ReportingCategory),
+    source,
+    bt.Description,
+    strings.ToUpper(string(bt.Currency)),
+    strconv.FormatInt(bt.Amount, 10),
+    strconv.FormatInt(bt.Fee, 10),
+    strconv.FormatInt(bt.Net, 10),
+    string(bt.Status),
+    exchangeRate,
+  }
+}
+
+// Streams balance transactions created in [from, to) as CSV, amounts are in
+// minor currency units
+func BalanceTransactionsCSV(
+  ctx context.Context, stp *stripe.Client, w io.Writer, from, to time.Time,
+) (int, error) {
+  cw := csv.NewWriter(w)
+  err := cw.Write(balanceCSVHeader)
+  if err != nil {
+    return 0, err
+  }
+  params := &stripe.BalanceTransactionListParams{
+    CreatedRange: &stripe.RangeQueryParams{
+      GreaterThanOrEqual: from.Unix(),
+      LesserThan: to.Unix(),
+    },
+  }
+  params.Limit = stripe.Int64(100)
+  count := 0
+  for bt, err := range stp.V1BalanceTransactions.List(ctx, params) {
+    if err != nil {
+      return count, Error(err)
+    }
+    err = cw.Write(balanceCSVRecord(bt))
+    if err != nil {
+      return count, err
+    }
+    count++
+  }
+  cw.Flush()
+  return count, cw.Error()
+}
+
+func balanceExportAction(
+  stripeKey string,
+) func(ctx context.Context, cmd *cli.Command) error {
+  return func(ctx context.Context, cmd *cli.Command) error {
+    // Arguments
+    from, err := time.Parse(time.DateOnly, cmd.String("from"))
+    if err != nil {
+      return errors.New("valid from date YYYY-MM-DD must be provided")
+    }
+    to, err := time.Parse(time.DateOnly, cmd.String("to"))
+    if err != nil || !to.After(from) {
+      return errors.New("valid to date YYYY-MM-DD after from must be provided")
+    }
+    format := cmd.String("format")
+    if format != "csv" {
+      return fmt.Errorf(
+        "unsupported format %s, only csv is supported, convert CSV to " +
+          "Parquet e.g. with DuckDB", format,
+      )
+    }
+    // Stripe
+    stp, err := NewClient(stripeKey)
+    if err != nil {
+      return err
+    }
+    // Output is closed explicitly, a failed close means a truncated export
+    out := io.Writer(os.Stdout)
+    var file *os.File
+    path := cmd.String("out")
+    if len(path) > 0 {
+      file, err = os.Create(path)
+      if err != nil {
+        return err
+      }
+      out = file
+    }
+    var gz *gzip.Writer
+    if cmd.Bool("gzip") {
+      gz = gzip.NewWriter(out)
+      out = gz
+    }
+    count, err := BalanceTransactionsCSV(ctx, stp, out, from, to)
+    if gz != nil {
+      err = errors.Join(err, gz.Close())
+    }
+    if file != nil {
+      err = errors.Join(err, file.Close())
+    }
+    if err != nil {
+      return err
+    }
+    fmt.Fprintf(os.Stderr, "=> %d balance transactions exported\n", count)
+    return nil
+  }
+}
+
+func BalanceExportCmd(stripeKey string) *cli.Command {
+  cmd := &cli.Command{
+    Name: "export",
+    Usage: "Export Stripe balance transactions to CSV",
+    Action: balanceExportAction(stripeKey),
+  }
+  cmd.Flags = []cli.Flag{
+    &cli.StringFlag{
+      Name: "from", Usage: "from date YYYY-MM-DD inclusive", Required: true,
+    },
+    &cli.StringFlag{
+      Name: "to", Usage: "to date YYYY-MM-DD exclusive", Required: true,
+    },
+    &cli.StringFlag{
+      Name: "out", Usage: "output file, stdout by default",
+    },
+    &cli.StringFlag{
+      Name: "format", Usage: "output format, only csv", Value: "csv",
+    },
+    &cli.BoolFlag{
+      Name: "gzip", Usage: "gzip output",
+    },
+  }
+  return cmd
+}
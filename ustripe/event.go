@@ -0,0 +1,72 @@
+package ustripe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/volodymyrprokopyuk/go-util/userv"
+)
+
+type EventHandler func(ctx context.Context, ev *stripe.Event) error
+
+// Dispatches webhook events by type, events without a handler are ignored
+type EventRouter struct {
+  mtx sync.RWMutex
+  handlers map[stripe.EventType]EventHandler
+  stages []Stage
+}
+
+func NewEventRouter() *EventRouter {
+  return &EventRouter{handlers: make(map[stripe.EventType]EventHandler)}
+}
+
+func (r *EventRouter) On(typ stripe.EventType, handler EventHandler) {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  r.handlers[typ] = handler
+}
+
+func (r *EventRouter) Types() []stripe.EventType {
+  r.mtx.RLock()
+  defer r.mtx.RUnlock()
+  types := make([]stripe.EventType, 0, len(r.handlers))
+  for typ := range r.handlers {
+    types = append(types, typ)
+  }
+  return types
+}
+
+func (r *EventRouter) Dispatch(ctx context.Context, ev *stripe.Event) error {
+  r.mtx.RLock()
+  handler, exist := r.handlers[ev.Type]
+  stages := r.stages
+  r.mtx.RUnlock()
+  if !exist {
+    return nil
+  }
+  err := Chain(handler, stages...)(ctx, ev)
+  if err != nil {
+    return fmt.Errorf("%s %s: %w", ev.Type, ev.ID, err)
+  }
+  return nil
+}
+
+// Verifies the signature and dispatches, failures are retried by Stripe
+func (r *EventRouter) Handler(whSecret string) http.HandlerFunc {
+  return func(w http.ResponseWriter, req *http.Request) {
+    ev, err := ReadEvent(req, whSecret)
+    if err != nil {
+      userv.WriteError(w, userv.BadRequest(err.Error()))
+      return
+    }
+    err = r.Dispatch(req.Context(), ev)
+    if err != nil {
+      userv.WriteError(w, err)
+      return
+    }
+    w.WriteHeader(http.StatusOK)
+  }
+}
@@ -0,0 +1,114 @@
+package ustripe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+type Card struct {
+  ID string `json:"id"`
+  Brand string `json:"brand"`
+  Last4 string `json:"last4"`
+  ExpMonth int `json:"expMonth"`
+  ExpYear int `json:"expYear"`
+  Default bool `json:"default"`
+}
+
+func cardFrom(pm *stripe.PaymentMethod, defaultID string) *Card {
+  card := &Card{ID: pm.ID, Default: pm.ID == defaultID}
+  if pm.Card != nil {
+    card.Brand = string(pm.Card.Brand)
+    card.Last4 = pm.Card.Last4
+    card.ExpMonth = int(pm.Card.ExpMonth)
+    card.ExpYear = int(pm.Card.ExpYear)
+  }
+  return card
+}
+
+func paymentMethodOwned(
+  ctx context.Context, stp *stripe.Client, customerID, pmID string,
+) (*stripe.PaymentMethod, error) {
+  pm, err := stp.V1PaymentMethods.Retrieve(ctx, pmID, nil)
+  if err != nil {
+    return nil, Error(err)
+  }
+  if pm.Customer == nil || pm.Customer.ID != customerID {
+    return nil, fmt.Errorf(
+      "payment method %s does not belong to customer %s", pmID, customerID,
+    )
+  }
+  return pm, nil
+}
+
+func PaymentMethodAttach(
+  ctx context.Context, stp *stripe.Client, customerID, pmID string,
+) (*Card, error) {
+  params := &stripe.PaymentMethodAttachParams{
+    Customer: stripe.String(customerID),
+  }
+  pm, err := stp.V1PaymentMethods.Attach(ctx, pmID, params)
+  if err != nil {
+    return nil, Error(err)
+  }
+  return cardFrom(pm, ""), nil
+}
+
+func PaymentMethodDetach(
+  ctx context.Context, stp *stripe.Client, customerID, pmID string,
+) error {
+  _, err := paymentMethodOwned(ctx, stp, customerID, pmID)
+  if err != nil {
+    return err
+  }
+  _, err = stp.V1PaymentMethods.Detach(ctx, pmID, nil)
+  if err != nil {
+    return Error(err)
+  }
+  return nil
+}
+
+func PaymentMethodDefault(
+  ctx context.Context, stp *stripe.Client, customerID, pmID string,
+) (*Card, error) {
+  pm, err := paymentMethodOwned(ctx, stp, customerID, pmID)
+  if err != nil {
+    return nil, err
+  }
+  params := &stripe.CustomerUpdateParams{
+    InvoiceSettings: &stripe.CustomerUpdateInvoiceSettingsParams{
+      DefaultPaymentMethod: stripe.String(pmID),
+    },
+  }
+  _, err = stp.V1Customers.Update(ctx, customerID, params)
+  if err != nil {
+    return nil, Error(err)
+  }
+  return cardFrom(pm, pmID), nil
+}
+
+func CustomerCards(
+  ctx context.Context, stp *stripe.Client, customerID string,
+) ([]*Card, error) {
+  cus, err := stp.V1Customers.Retrieve(ctx, customerID, nil)
+  if err != nil {
+    return nil, Error(err)
+  }
+  var defaultID string
+  if cus.InvoiceSettings != nil && cus.InvoiceSettings.DefaultPaymentMethod != nil {
+    defaultID = cus.InvoiceSettings.DefaultPaymentMethod.ID
+  }
+  params := &stripe.CustomerListPaymentMethodsParams{
+    Customer: stripe.String(customerID),
+    Type: stripe.String(string(stripe.PaymentMethodTypeCard)),
+  }
+  var cards []*Card
+  for pm, err := range stp.V1Customers.ListPaymentMethods(ctx, params) {
+    if err != nil {
+      return nil, Error(err)
+    }
+    cards = append(cards, cardFrom(pm, defaultID))
+  }
+  return cards, nil
+}
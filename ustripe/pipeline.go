@@ -0,0 +1,143 @@
+package ustripe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/volodymyrprokopyuk/go-util/ucache"
+)
+
+// Runs before the handler, filters return nil without calling next to drop
+// the event
+type Stage func(next EventHandler) EventHandler
+
+// Stages run in order before every handler of the router
+func (r *EventRouter) Use(stages ...Stage) {
+  r.mtx.Lock()
+  defer r.mtx.Unlock()
+  r.stages = append(r.stages, stages...)
+}
+
+// Per-handler stages e.g. r.On(typ, Chain(handler, Metadata("tenant", "a")))
+func Chain(handler EventHandler, stages ...Stage) EventHandler {
+  for i := len(stages) - 1; i >= 0; i-- {
+    handler = stages[i](handler)
+  }
+  return handler
+}
+
+func Filter(match func(ev *stripe.Event) bool) Stage {
+  return func(next EventHandler) EventHandler {
+    return func(ctx context.Context, ev *stripe.Event) error {
+      if !match(ev) {
+        return nil
+      }
+      return next(ctx, ev)
+    }
+  }
+}
+
+func Livemode(live bool) Stage {
+  return Filter(func(ev *stripe.Event) bool {
+    return ev.Livemode == live
+  })
+}
+
+// Connected accounts, the platform account is empty
+func FromAccounts(accounts ...string) Stage {
+  return Filter(func(ev *stripe.Event) bool {
+    return slices.Contains(accounts, ev.Account)
+  })
+}
+
+// The event object metadata key must have the value
+func Metadata(key, value string) Stage {
+  return Filter(func(ev *stripe.Event) bool {
+    if ev.Data == nil {
+      return false
+    }
+    meta, _ := ev.Data.Object["metadata"].(map[string]any)
+    val, _ := meta[key].(string)
+    return val == value
+  })
+}
+
+type enrichKey struct {
+  name string
+}
+
+// Looks up extra data once per key, e.g. the customer of an invoice, caches
+// it for the ttl and stores it in the context for Enriched
+func Enrich[T any](
+  name string, size int, ttl time.Duration,
+  keyFunc func(ev *stripe.Event) string,
+  lookup func(ctx context.Context, key string) (T, error),
+) Stage {
+  cache := ucache.New[string, T](size)
+  return func(next EventHandler) EventHandler {
+    return func(ctx context.Context, ev *stripe.Event) error {
+      key := keyFunc(ev)
+      if len(key) == 0 {
+        return next(ctx, ev)
+      }
+      val, exist := cache.Get(key)
+      if !exist {
+        var err error
+        val, err = lookup(ctx, key)
+        if err != nil {
+          return fmt.Errorf("enrich %s %s: %w", name, key, err)
+        }
+        cache.Set(key, val, ttl)
+      }
+      ctx = context.WithValue(ctx, enrichKey{name: name}, val)
+      return next(ctx, ev)
+    }
+  }
+}
+
+func Enriched[T any](ctx context.Context, name string) (T, bool) {
+  val, ok := ctx.Value(enrichKey{name: name}).(T)
+  return val, ok
+}
+
+// Top-level string field of the event object e.g. ObjectField("customer")
+func ObjectField(name string) func(ev *stripe.Event) string {
+  return func(ev *stripe.Event) string {
+    if ev.Data == nil {
+      return ""
+    }
+    val, _ := ev.Data.Object[name].(string)
+    return val
+  }
+}
+
+// Decodes the event object into the Stripe type e.g. stripe.Invoice
+func DecodeObject[T any](ctx context.Context, ev *stripe.Event) (*T, error) {
+  if ev.Data == nil {
+    return nil, fmt.Errorf("%s %s: missing data", ev.Type, ev.ID)
+  }
+  var val T
+  err := json.Unmarshal(ev.Data.Raw, &val)
+  if err != nil {
+    return nil, err
+  }
+  return &val, nil
+}
+
+// Transforms the raw event into an internal event for the business handler
+func Transform[T any](
+  transform func(ctx context.Context, ev *stripe.Event) (*T, error),
+  handler func(ctx context.Context, val *T) error,
+) EventHandler {
+  return func(ctx context.Context, ev *stripe.Event) error {
+    val, err := transform(ctx, ev)
+    if err != nil {
+      return err
+    }
+    return handler(ctx, val)
+  }
+}
@@ -0,0 +1,210 @@
+// Canned Stripe events per API version and contract tests of ustripe event
+// routers against them
+package stripetest
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stripe/stripe-go/v82"
+	"github.com/volodymyrprokopyuk/go-util/ustripe"
+)
+
+//go:embed fixtures
+var fixturesFS embed.FS
+
+type fixtureConfig struct {
+  version string
+  overrides []func(obj map[string]any)
+}
+
+type fixtureOption func(cfg *fixtureConfig)
+
+// API version of the fixtures, the stripe-go pinned version by default
+func FixtureVersion(version string) fixtureOption {
+  return func(cfg *fixtureConfig) {
+    cfg.version = version
+  }
+}
+
+func FixtureOverride(override func(obj map[string]any)) fixtureOption {
+  return func(cfg *fixtureConfig) {
+    cfg.overrides = append(cfg.overrides, override)
+  }
+}
+
+// Sets a dotted path e.g. FixtureSet("metadata.order_id", "ord_1")
+func FixtureSet(path string, value any) fixtureOption {
+  return FixtureOverride(func(obj map[string]any) {
+    names := strings.Split(path, ".")
+    for _, name := range names[:len(names) - 1] {
+      next, ok := obj[name].(map[string]any)
+      if !ok {
+        next = make(map[string]any)
+        obj[name] = next
+      }
+      obj = next
+    }
+    obj[names[len(names) - 1]] = value
+  })
+}
+
+func newFixtureConfig(opts ...fixtureOption) *fixtureConfig {
+  cfg := &fixtureConfig{version: stripe.APIVersion}
+  for _, opt := range opts {
+    opt(cfg)
+  }
+  return cfg
+}
+
+func fixtureObject(
+  version string, typ stripe.EventType,
+) (map[string]any, error) {
+  data, err := fixturesFS.ReadFile(
+    fmt.Sprintf("fixtures/%s/%s.json", version, typ),
+  )
+  if err != nil {
+    return nil, fmt.Errorf("no %s fixture for API version %s", typ, version)
+  }
+  var obj map[string]any
+  err = json.Unmarshal(data, &obj)
+  if err != nil {
+    return nil, err
+  }
+  return obj, nil
+}
+
+func fixtureEvent(
+  version string, typ stripe.EventType, obj map[string]any,
+) (*stripe.Event, error) {
+  jev, err := json.Marshal(map[string]any{
+    "id": "evt_fixture_" + strings.ReplaceAll(string(typ), ".", "_"),
+    "object": "event",
+    "api_version": version,
+    "created": obj["created"],
+    "livemode": false,
+    "pending_webhooks": 1,
+    "type": typ,
+    "data": map[string]any{"object": obj},
+  })
+  if err != nil {
+    return nil, err
+  }
+  var ev stripe.Event
+  err = json.Unmarshal(jev, &ev)
+  if err != nil {
+    return nil, err
+  }
+  return &ev, nil
+}
+
+// Canned event of the API version with the object overrides applied
+func EventFixture(
+  typ stripe.EventType, opts ...fixtureOption,
+) (*stripe.Event, error) {
+  cfg := newFixtureConfig(opts...)
+  obj, err := fixtureObject(cfg.version, typ)
+  if err != nil {
+    return nil, err
+  }
+  for _, override := range cfg.overrides {
+    override(obj)
+  }
+  return fixtureEvent(cfg.version, typ, obj)
+}
+
+func FixtureTypes(version string) []stripe.EventType {
+  entries, _ := fixturesFS.ReadDir("fixtures/" + version)
+  types := make([]stripe.EventType, 0, len(entries))
+  for _, entry := range entries {
+    name := strings.TrimSuffix(entry.Name(), ".json")
+    types = append(types, stripe.EventType(name))
+  }
+  return types
+}
+
+// Adds an unknown field to every nested object
+func withUnknownFields(val any) {
+  switch v := val.(type) {
+  case map[string]any:
+    for _, item := range v {
+      withUnknownFields(item)
+    }
+    v["fixture_unknown_field"] = "unknown"
+  case []any:
+    for _, item := range v {
+      withUnknownFields(item)
+    }
+  }
+}
+
+// Removes nullable fields and empty objects
+func withoutOptionalFields(val any) {
+  switch v := val.(type) {
+  case map[string]any:
+    for key, item := range v {
+      obj, isObj := item.(map[string]any)
+      if item == nil || isObj && len(obj) == 0 {
+        delete(v, key)
+        continue
+      }
+      withoutOptionalFields(item)
+    }
+  case []any:
+    for _, item := range v {
+      withoutOptionalFields(item)
+    }
+  }
+}
+
+func dispatchFixture(
+  ctx context.Context, router *ustripe.EventRouter, ev *stripe.Event,
+) (err error) {
+  defer func() {
+    if rec := recover(); rec != nil {
+      err = fmt.Errorf("panic: %v", rec)
+    }
+  }()
+  return router.Dispatch(ctx, ev)
+}
+
+// Asserts router handlers accept each fixture as is, with unknown fields and
+// without optional fields
+func ContractTest(t testing.TB, router *ustripe.EventRouter, opts ...fixtureOption) {
+  t.Helper()
+  cfg := newFixtureConfig(opts...)
+  variants := []struct{
+    name string
+    mutate func(val any)
+  }{
+    {"canonical", func(val any) {}},
+    {"unknown fields", withUnknownFields},
+    {"missing optional fields", withoutOptionalFields},
+  }
+  for _, typ := range router.Types() {
+    for _, variant := range variants {
+      obj, err := fixtureObject(cfg.version, typ)
+      if err != nil {
+        t.Logf("%s: skipped: %s", typ, err)
+        break
+      }
+      for _, override := range cfg.overrides {
+        override(obj)
+      }
+      variant.mutate(obj)
+      ev, err := fixtureEvent(cfg.version, typ, obj)
+      if err != nil {
+        t.Errorf("%s %s: %s", typ, variant.name, err)
+        continue
+      }
+      err = dispatchFixture(t.Context(), router, ev)
+      if err != nil {
+        t.Errorf("%s %s: %s", typ, variant.name, err)
+      }
+    }
+  }
+}